@@ -1,6 +1,9 @@
 package cluster
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"io/ioutil"
 	"os/user"
 	"path/filepath"
@@ -11,7 +14,10 @@ import (
 
 	"github.com/dimaunx/armada/pkg/cluster"
 	"github.com/dimaunx/armada/pkg/defaults"
+	"github.com/dimaunx/armada/pkg/image"
+	dockerclient "github.com/docker/docker/client"
 	"github.com/gobuffalo/packr/v2"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	kind "sigs.k8s.io/kind/pkg/cluster"
@@ -22,6 +28,9 @@ type CreateClusterFlagpole struct {
 	// ImageName is the node image used for cluster creation
 	ImageName string
 
+	// K8sVersion is the kubernetes version used to select the node image from the catalog
+	K8sVersion string
+
 	// Wait is a time duration to wait until cluster is ready
 	Wait time.Duration
 
@@ -40,6 +49,30 @@ type CreateClusterFlagpole struct {
 	// Kindnet if to install kindnet default cni
 	Kindnet bool
 
+	// Cni is the cni name and optional version, eg: calico@3.9, overrides the cni boolean flags
+	Cni string
+
+	// CniMTU is the pod network mtu
+	CniMTU int
+
+	// CalicoEncapsulation is the calico ip pool encapsulation: IPIP, VXLAN or None
+	CalicoEncapsulation string
+
+	// CalicoCrossSubnet if calico encapsulates only the traffic crossing subnets
+	CalicoCrossSubnet bool
+
+	// FlannelBackend is the flannel backend: vxlan or host-gw
+	FlannelBackend string
+
+	// WeavePassword enables weave encryption
+	WeavePassword string
+
+	// WeaveFastDatapath if weave uses fast datapath
+	WeaveFastDatapath bool
+
+	// KubeProxyMode is the kube-proxy mode: iptables, ipvs or disabled
+	KubeProxyMode string
+
 	// DeployTiller if to install tiller
 	Tiller bool
 
@@ -51,6 +84,18 @@ type CreateClusterFlagpole struct {
 
 	// NumClusters is the number of clusters to create
 	NumClusters int
+
+	// DryRun if to render the cluster configs and manifests without creating the clusters
+	DryRun bool
+
+	// RenderDir is the destination directory for the rendered files, used by dry run and armada diff
+	RenderDir string
+
+	// PreloadImages if to load the local images of the cni and addon manifests to the nodes before deploying them
+	PreloadImages bool
+
+	// SideloadImages if the cni images must be side loaded, for hosts without registry access
+	SideloadImages bool
 }
 
 // CreateClustersCommand returns a new cobra.Command under create command for armada
@@ -68,11 +113,58 @@ func CreateClustersCommand(provider *kind.Provider, box *packr.Box) *cobra.Comma
 				//log.SetReportCaller(true)
 			}
 
-			targetClusters, err := GetTargetClusters(provider, flags)
+			if flags.K8sVersion != "" {
+				if flags.ImageName != "" {
+					log.Fatal("--image and --k8s-version are mutually exclusive")
+				}
+				catalog, err := image.LoadNodeImageCatalog(box)
+				if err != nil {
+					log.Fatal(err)
+				}
+				nodeImage, err := catalog.Find(flags.K8sVersion)
+				if err != nil {
+					log.Fatal(err)
+				}
+				if nodeImage.Digest == "" {
+					log.Warnf("No pinned digest for %s, using the image tag.", nodeImage.Image)
+				}
+				flags.ImageName = nodeImage.Reference()
+				log.Infof("Using node image %s for kubernetes %s.", flags.ImageName, flags.K8sVersion)
+			}
+
+			if flags.DryRun {
+				cluster.SetInspectNodeImages(false)
+			}
+
+			targetClusters, err := GetTargetClusters(provider, box, flags)
 			if err != nil {
 				log.Fatal(err)
 			}
 
+			if flags.DryRun {
+				return RenderClusters(targetClusters, box, flags.RenderDir)
+			}
+
+			// keep the rendered manifests of the created clusters for armada diff
+			renderDir := flags.RenderDir
+			if renderDir == "" {
+				renderDir = defaults.HomePath(defaults.RenderDir)
+			}
+			for _, cl := range targetClusters {
+				if err := cluster.Render(cl, box, renderDir); err != nil {
+					log.Fatalf("%s: %s", cl.Name, err)
+				}
+			}
+
+			for _, cl := range targetClusters {
+				if cl.KubeProxyMode == cluster.KubeProxyModeIPVS {
+					if err := cluster.CheckIPVSKernelModules(); err != nil {
+						log.Fatal(err)
+					}
+					break
+				}
+			}
+
 			var wg sync.WaitGroup
 			wg.Add(len(targetClusters))
 			for _, cl := range targetClusters {
@@ -86,6 +178,19 @@ func CreateClustersCommand(provider *kind.Provider, box *packr.Box) *cobra.Comma
 			}
 			wg.Wait()
 
+			// the cni pods can not start on hosts without registry access unless their images are side loaded
+			if flags.SideloadImages {
+				if err := SideloadCniImages(provider, box, targetClusters); err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			if flags.PreloadImages {
+				if err := PreloadImages(provider, box, targetClusters); err != nil {
+					log.Fatal(err)
+				}
+			}
+
 			log.Info("Finalizing the clusters setup ...")
 			wg.Add(len(targetClusters))
 			for _, cl := range targetClusters {
@@ -101,15 +206,18 @@ func CreateClustersCommand(provider *kind.Provider, box *packr.Box) *cobra.Comma
 			return nil
 		},
 		PersistentPostRun: func(cmd *cobra.Command, args []string) {
-			files, err := ioutil.ReadDir(defaults.KindConfigDir)
+			if flags.DryRun {
+				return
+			}
+
+			clNames, err := cluster.GetTargetClusterNames(nil)
 			if err != nil {
 				log.Fatal(err)
 			}
 
 			provider := kind.NewProvider()
 
-			for _, file := range files {
-				clName := strings.FieldsFunc(file.Name(), func(r rune) bool { return strings.ContainsRune(" -.", r) })[2]
+			for _, clName := range clNames {
 				known, err := cluster.IsKnown(clName, provider)
 				if err != nil {
 					log.Error(err)
@@ -134,31 +242,53 @@ func CreateClustersCommand(provider *kind.Provider, box *packr.Box) *cobra.Comma
 					}
 				}
 			}
-			log.Infof("✔ Kubeconfigs: export KUBECONFIG=$(echo ./%s/kind-config-%s{1..%v} | sed 's/ /:/g')", defaults.LocalKubeConfigDir, defaults.ClusterNameBase, flags.NumClusters)
+			kubeConfigFilePath, err := cluster.GetKubeConfigPath(defaults.ClusterNameBase)
+			if err != nil {
+				log.Fatal(err)
+			}
+			log.Infof("✔ Kubeconfigs: export KUBECONFIG=$(echo %s{1..%v} | sed 's/ /:/g')", kubeConfigFilePath, flags.NumClusters)
 		},
 	}
 	cmd.Flags().StringVarP(&flags.ImageName, "image", "i", "", "node docker image to use for booting the cluster")
+	cmd.Flags().StringVar(&flags.K8sVersion, "k8s-version", "", "kubernetes version, selects the pinned node image from 'armada images node list'")
 	cmd.Flags().BoolVarP(&flags.Retain, "retain", "", true, "retain nodes for debugging when cluster creation fails")
 	cmd.Flags().BoolVarP(&flags.Weave, "weave", "w", false, "deploy with weave")
 	cmd.Flags().BoolVarP(&flags.Tiller, "tiller", "t", false, "deploy with tiller")
+	_ = cmd.Flags().MarkDeprecated("tiller", "helm 2 is end of life, use 'armada deploy chart' to install helm 3 charts")
 	cmd.Flags().BoolVarP(&flags.Calico, "calico", "c", false, "deploy with calico")
 	cmd.Flags().BoolVarP(&flags.Kindnet, "kindnet", "k", true, "deploy with kindnet default cni")
+	cmd.Flags().StringVar(&flags.Cni, "cni", "", "cni name and optional version to deploy, eg: calico@3.9, flannel, weave@2.5.2")
+	cmd.Flags().IntVar(&flags.CniMTU, "cni-mtu", 0, "pod network mtu (default cni specific)")
+	cmd.Flags().StringVar(&flags.CalicoEncapsulation, "calico-encapsulation", cluster.CalicoEncapsulationIPIP, "calico ip pool encapsulation: IPIP, VXLAN or None for BGP only")
+	cmd.Flags().BoolVar(&flags.CalicoCrossSubnet, "calico-cross-subnet", false, "calico encapsulates only the traffic crossing subnets")
+	cmd.Flags().StringVar(&flags.FlannelBackend, "flannel-backend", cluster.FlannelBackendVxlan, "flannel backend: vxlan or host-gw")
+	cmd.Flags().StringVar(&flags.WeavePassword, "weave-password", "", "weave encryption password")
+	cmd.Flags().BoolVar(&flags.WeaveFastDatapath, "weave-fastdp", true, "weave fast datapath")
+	cmd.Flags().StringVar(&flags.KubeProxyMode, "kube-proxy-mode", cluster.KubeProxyModeIPTables, "kube-proxy mode: iptables, ipvs or disabled")
 	cmd.Flags().BoolVarP(&flags.Flannel, "flannel", "f", false, "deploy with flannel")
 	cmd.Flags().BoolVarP(&flags.Overlap, "overlap", "o", false, "create clusters with overlapping cidrs")
 	cmd.Flags().BoolVarP(&flags.Debug, "debug", "v", false, "set log level to debug")
 	cmd.Flags().DurationVar(&flags.Wait, "wait", 5*time.Minute, "amount of minutes to wait for control plane nodes to be ready")
 	cmd.Flags().IntVarP(&flags.NumClusters, "num", "n", 2, "number of clusters to create")
+	cmd.Flags().BoolVar(&flags.DryRun, "dry-run", false, "render the kind configs and manifests of the clusters without creating them")
+	cmd.Flags().BoolVar(&flags.SideloadImages, "sideload-images", false, "side load the cni images from the local docker daemon or the image cache, fail if one is in neither. For hosts without registry access")
+	cmd.Flags().BoolVar(&flags.PreloadImages, "preload-images", false, "load the local images of the cni and addon manifests to the nodes before deploying them")
+	cmd.Flags().StringVar(&flags.RenderDir, "render-dir", "", "destination directory of the rendered cluster configs and manifests (default <armada home>/render)")
 	return cmd
 }
 
 // GetTargetClusters returns a list of clusters to create
-func GetTargetClusters(provider *kind.Provider, flags *CreateClusterFlagpole) ([]*cluster.Config, error) {
+func GetTargetClusters(provider *kind.Provider, box *packr.Box, flags *CreateClusterFlagpole) ([]*cluster.Config, error) {
 	var targetClusters []*cluster.Config
 	for i := 1; i <= flags.NumClusters; i++ {
 		clName := defaults.ClusterNameBase + strconv.Itoa(i)
-		known, err := cluster.IsKnown(clName, provider)
-		if err != nil {
-			return nil, err
+		known := false
+		if !flags.DryRun {
+			var err error
+			known, err = cluster.IsKnown(clName, provider)
+			if err != nil {
+				return nil, err
+			}
 		}
 		if known {
 			log.Infof("✔ Cluster with the name %q already exists.", clName)
@@ -168,16 +298,120 @@ func GetTargetClusters(provider *kind.Provider, flags *CreateClusterFlagpole) ([
 			if err != nil {
 				return nil, err
 			}
+
+			if err := cluster.SetCniVersion(cl, box); err != nil {
+				return nil, err
+			}
+
+			cl.CniMTU = flags.CniMTU
+			cl.Calico = cluster.CalicoOptions{Encapsulation: flags.CalicoEncapsulation, CrossSubnet: flags.CalicoCrossSubnet}
+			cl.Flannel = cluster.FlannelOptions{Backend: flags.FlannelBackend}
+			cl.Weave = cluster.WeaveOptions{Password: flags.WeavePassword, DisableFastDatapath: !flags.WeaveFastDatapath}
+			if err := cluster.ValidateCniOptions(cl); err != nil {
+				return nil, err
+			}
+
+			cl.KubeProxyMode = flags.KubeProxyMode
+			if err := cluster.ValidateKubeProxyMode(cl); err != nil {
+				return nil, err
+			}
 			targetClusters = append(targetClusters, cl)
 		}
 	}
 	return targetClusters, nil
 }
 
+// SideloadCniImages loads the cni images missing on the nodes of the clusters from the local docker daemon or the image cache
+func SideloadCniImages(provider *kind.Provider, box *packr.Box, targetClusters []*cluster.Config) error {
+	ctx := context.Background()
+	dockerCli, err := dockerclient.NewEnvClient()
+	if err != nil {
+		return err
+	}
+	defer dockerCli.Close()
+
+	for _, cl := range targetClusters {
+		images, err := cluster.CniImages(cl, box)
+		if err != nil {
+			return errors.Wrapf(err, "%q", cl.Name)
+		}
+		if len(images) == 0 {
+			continue
+		}
+
+		err = image.SideloadImages(ctx, dockerCli, provider, cl.Name, images, defaults.HomePath(defaults.ImageCacheDir), image.DefaultLoadConcurrency)
+		if err != nil {
+			return errors.Wrapf(err, "%s cni", cl.Cni)
+		}
+	}
+	return nil
+}
+
+// PreloadImages loads the container images of the cni and addon manifests of the clusters that exist locally to their nodes
+func PreloadImages(provider *kind.Provider, box *packr.Box, targetClusters []*cluster.Config) error {
+	ctx := context.Background()
+	dockerCli, err := dockerclient.NewEnvClient()
+	if err != nil {
+		return err
+	}
+	defer dockerCli.Close()
+
+	var clNames []string
+	var images []string
+	seen := map[string]bool{}
+	for _, cl := range targetClusters {
+		clNames = append(clNames, cl.Name)
+		clImages, err := cluster.ManifestImages(cl, box)
+		if err != nil {
+			return errors.Wrapf(err, "%q", cl.Name)
+		}
+		for _, img := range clImages {
+			if !seen[img] {
+				seen[img] = true
+				images = append(images, img)
+			}
+		}
+	}
+	if len(images) == 0 {
+		return nil
+	}
+
+	log.Infof("Preloading images: %s ...", strings.Join(images, ", "))
+	return image.LoadLocalImages(ctx, dockerCli, provider, images, clNames, image.DefaultLoadConcurrency)
+}
+
+// RenderClusters writes the kind configs and manifests of the clusters to renderDir and prints the summary
+func RenderClusters(targetClusters []*cluster.Config, box *packr.Box, renderDir string) error {
+	if renderDir == "" {
+		renderDir = defaults.HomePath(defaults.RenderDir)
+	}
+
+	for _, cl := range targetClusters {
+		if err := cluster.Render(cl, box, renderDir); err != nil {
+			return errors.Wrapf(err, "%q", cl.Name)
+		}
+	}
+
+	var summary bytes.Buffer
+	if err := cluster.WriteRenderSummary(&summary, targetClusters); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(renderDir, "summary.txt"), summary.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	fmt.Print(summary.String())
+	log.Infof("✔ Dry run, nothing was created. Rendered files: %s", renderDir)
+	return nil
+}
+
 // GetCniFromFlags returns the cni name from flags
 func GetCniFromFlags(flags *CreateClusterFlagpole) string {
 	var cni string
-	if flags.Weave {
+	if flags.Cni != "" {
+		cni = flags.Cni
+	} else if flags.Weave {
 		cni = "weave"
 	} else if flags.Flannel {
 		cni = "flannel"
@@ -0,0 +1,177 @@
+package chart
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/dimaunx/armada/pkg/defaults"
+	"github.com/dimaunx/armada/pkg/deploy"
+	"github.com/dimaunx/armada/pkg/helm"
+	"github.com/dimaunx/armada/pkg/wait"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// ChartDeployFlagpole is a list of cli flags for deploy chart command
+type ChartDeployFlagpole struct {
+	// ValuesFiles are the release values files, rendered as go templates for each cluster
+	ValuesFiles []string
+
+	// ReleaseName is the release name, the chart name if empty
+	ReleaseName string
+
+	// Namespace is the release namespace, created if missing
+	Namespace string
+
+	// Helm is the helm 3 binary
+	Helm string
+
+	// Timeout is how long to wait for the release resources
+	Timeout time.Duration
+
+	// Clusters is a list of cluster names to deploy to, all clusters if empty
+	Clusters []string
+
+	// Debug sets log level to debug
+	Debug bool
+}
+
+// DeployChartCommand returns a new cobra.Command under deploy command for armada
+func DeployChartCommand() *cobra.Command {
+	flags := &ChartDeployFlagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Use:   "chart <chart dir or .tgz>",
+		Short: "Deploy a helm 3 chart",
+		Long: "Install or upgrade a local helm 3 chart release in the clusters with the helm binary, which must be installed, and wait for the release resources. " +
+			"The values files are rendered as go templates for each cluster like the manifests of deploy manifests command",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if flags.Debug {
+				log.SetLevel(log.DebugLevel)
+			}
+
+			chart := args[0]
+			if err := helm.CheckVersion(flags.Helm); err != nil {
+				log.Fatal(err)
+			}
+
+			releaseName := flags.ReleaseName
+			if releaseName == "" {
+				var err error
+				releaseName, err = helm.ChartName(chart)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			valuesFiles, err := deploy.ReadManifestFiles(flags.ValuesFiles...)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			configs, err := cluster.GetClusterConfigs(targetClusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			opts := helm.InstallOptions{ReleaseName: releaseName, Namespace: flags.Namespace, Timeout: flags.Timeout}
+
+			var wg sync.WaitGroup
+			wg.Add(len(targetClusters))
+			for _, clName := range targetClusters {
+				go func(cl *cluster.Config) {
+					err := Chart(cl, cluster.SortConfigs(configs), chart, flags.Helm, valuesFiles, opts)
+					if err != nil {
+						log.Fatalf("%s %s", cl.Name, err)
+					}
+					log.Infof("✔ Release %s was deployed to %s.", releaseName, cl.Name)
+					wg.Done()
+				}(configs[clName])
+			}
+			wg.Wait()
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(&flags.ValuesFiles, "values", []string{}, "release values files, rendered as go templates for each cluster")
+	cmd.Flags().StringVar(&flags.ReleaseName, "name", "", "release name (default the chart name)")
+	cmd.Flags().StringVarP(&flags.Namespace, "namespace", "n", "default", "release namespace, created if missing")
+	cmd.Flags().StringVar(&flags.Helm, "helm", "helm", "helm 3 binary")
+	cmd.Flags().DurationVar(&flags.Timeout, "timeout", defaults.WaitDurationResources, "how long to wait for the release resources")
+	cmd.Flags().StringSliceVarP(&flags.Clusters, "clusters", "c", []string{}, "comma separated list of cluster names to deploy to. eg: cl1,cl6,cl3")
+	cmd.Flags().BoolVarP(&flags.Debug, "debug", "v", false, "set log level to debug")
+	return cmd
+}
+
+// Chart renders the values files for the cluster and installs the chart release
+func Chart(cl *cluster.Config, peers []*cluster.Config, chart, helmPath string, valuesFiles []deploy.ManifestFile, opts helm.InstallOptions) error {
+	valuesDir, err := ioutil.TempDir("", "armada-values-"+cl.Name)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(valuesDir)
+
+	for i, valuesFile := range valuesFiles {
+		rendered, err := cluster.RenderManifest(filepath.Base(valuesFile.Path), valuesFile.Content, cl, peers)
+		if err != nil {
+			return err
+		}
+
+		renderedPath := filepath.Join(valuesDir, fmt.Sprintf("%02d-%s", i+1, filepath.Base(valuesFile.Path)))
+		if err := ioutil.WriteFile(renderedPath, []byte(rendered), 0600); err != nil {
+			return err
+		}
+		opts.ValuesFiles = append(opts.ValuesFiles, renderedPath)
+	}
+
+	dynamicClient, mapper, err := cluster.GetDynamicClient(cl.Name)
+	if err != nil {
+		return err
+	}
+
+	err = deploy.EnsureNamespace(cl.Name, dynamicClient, mapper, opts.Namespace)
+	if err != nil {
+		return err
+	}
+
+	kubeConfigPath, err := cluster.GetKubeConfigPath(cl.Name)
+	if err != nil {
+		return err
+	}
+	err = helm.Install(cl.Name, helmPath, kubeConfigPath, chart, opts)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := helm.Manifest(helmPath, kubeConfigPath, opts.ReleaseName, opts.Namespace)
+	if err != nil {
+		return err
+	}
+	objects, err := deploy.Decode(manifest)
+	if err != nil {
+		return errors.Wrapf(err, "release %s", opts.ReleaseName)
+	}
+	// namespaced resources of a chart without a namespace are installed to the release namespace
+	for _, obj := range objects {
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(opts.Namespace)
+		}
+	}
+
+	clientSet, err := cluster.GetClientSet(cl.Name)
+	if err != nil {
+		return err
+	}
+	return wait.ForObjectsReady(cl.Name, clientSet, dynamicClient, mapper, objects, opts.Timeout)
+}
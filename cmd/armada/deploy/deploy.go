@@ -1,6 +1,8 @@
 package deploy
 
 import (
+	"github.com/dimaunx/armada/cmd/armada/deploy/chart"
+	"github.com/dimaunx/armada/cmd/armada/deploy/manifests"
 	"github.com/dimaunx/armada/cmd/armada/deploy/netshoot"
 	"github.com/dimaunx/armada/cmd/armada/deploy/nginx"
 	"github.com/gobuffalo/packr/v2"
@@ -15,6 +17,8 @@ func DeployCmd(box *packr.Box) *cobra.Command {
 		Short: "Deploy resources",
 		Long:  "Deploy resources",
 	}
+	cmd.AddCommand(chart.DeployChartCommand())
+	cmd.AddCommand(manifests.DeployManifestsCommand())
 	cmd.AddCommand(netshoot.DeployNetshootCommand(box))
 	cmd.AddCommand(nginx.DeployNginxDemoCommand(box))
 	return cmd
@@ -0,0 +1,120 @@
+package manifests
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/dimaunx/armada/pkg/defaults"
+	"github.com/dimaunx/armada/pkg/deploy"
+	"github.com/dimaunx/armada/pkg/wait"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// ManifestsDeployFlagpole is a list of cli flags for deploy manifests command
+type ManifestsDeployFlagpole struct {
+	// Files is a list of manifest template files or directories
+	Files []string
+
+	// Clusters is a list of cluster names to deploy to, all clusters if empty
+	Clusters []string
+
+	// Wait if to wait for the deployed objects to be ready
+	Wait bool
+
+	// Timeout is how long to wait for the deployed objects to be ready
+	Timeout time.Duration
+
+	// Debug sets log level to debug
+	Debug bool
+}
+
+// DeployManifestsCommand returns a new cobra.Command under deploy command for armada
+func DeployManifestsCommand() *cobra.Command {
+	flags := &ManifestsDeployFlagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "manifests",
+		Short: "Deploy manifests rendered for each cluster",
+		Long: "Deploy manifest files rendered as go templates for each cluster. The templates get the cluster config, " +
+			"eg: {{.Name}}, {{.PodSubnet}}, {{.ServiceSubnet}}, {{.DNSDomain}}, {{.Cni}}, and the other clusters configs as {{.Peers}}",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if flags.Debug {
+				log.SetLevel(log.DebugLevel)
+			}
+
+			if len(flags.Files) == 0 {
+				log.Fatal("at least one manifest file or directory is required")
+			}
+
+			manifests, err := deploy.ReadManifestFiles(flags.Files...)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			configs, err := cluster.GetClusterConfigs(targetClusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			var wg sync.WaitGroup
+			wg.Add(len(targetClusters))
+			for _, clName := range targetClusters {
+				go func(cl *cluster.Config) {
+					err := Manifests(cl, configs, manifests, flags.Wait, flags.Timeout)
+					if err != nil {
+						log.Fatalf("%s %s", cl.Name, err)
+					}
+					log.Infof("✔ Manifests were deployed to %s.", cl.Name)
+					wg.Done()
+				}(configs[clName])
+			}
+			wg.Wait()
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&flags.Files, "filename", "f", []string{}, "manifest template files or directories to deploy")
+	cmd.Flags().StringSliceVarP(&flags.Clusters, "clusters", "c", []string{}, "comma separated list of cluster names to deploy to. eg: cl1,cl6,cl3")
+	cmd.Flags().BoolVar(&flags.Wait, "wait", true, "wait for the deployed objects to be ready")
+	cmd.Flags().DurationVar(&flags.Timeout, "timeout", defaults.WaitDurationResources, "how long to wait for the deployed objects to be ready")
+	cmd.Flags().BoolVarP(&flags.Debug, "debug", "v", false, "set log level to debug")
+	return cmd
+}
+
+// Manifests renders the manifests for the cluster, applies them and waits up to timeout for the applied objects to be ready
+func Manifests(cl *cluster.Config, configs map[string]*cluster.Config, manifests []deploy.ManifestFile, waitReady bool, timeout time.Duration) error {
+	peers := cluster.SortConfigs(configs)
+
+	dynamicClient, mapper, err := cluster.GetDynamicClient(cl.Name)
+	if err != nil {
+		return err
+	}
+
+	// the objects of all the files are applied together so CRDs are established before the resources that need them
+	objects, err := cluster.RenderManifestObjects(cl, peers, manifests)
+	if err != nil {
+		return err
+	}
+
+	err = deploy.ApplyObjects(cl.Name, dynamicClient, mapper, objects)
+	if err != nil {
+		return err
+	}
+
+	if !waitReady {
+		return nil
+	}
+
+	clientSet, err := cluster.GetClientSet(cl.Name)
+	if err != nil {
+		return err
+	}
+	return wait.ForObjectsReady(cl.Name, clientSet, dynamicClient, mapper, objects, timeout)
+}
@@ -1,9 +1,8 @@
 package netshoot
 
 import (
-	"io/ioutil"
-	"strings"
 	"sync"
+	"time"
 
 	"github.com/dimaunx/armada/pkg/cluster"
 	"github.com/dimaunx/armada/pkg/defaults"
@@ -19,6 +18,7 @@ type NetshootDeployFlagpole struct {
 	HostNetwork bool
 	Debug       bool
 	Clusters    []string
+	Timeout     time.Duration
 }
 
 // DeployNetshootCommand returns a new cobra.Command under deploy command for armada
@@ -50,18 +50,9 @@ func DeployNetshootCommand(box *packr.Box) *cobra.Command {
 				log.Error(err)
 			}
 
-			var targetClusters []string
-			if len(flags.Clusters) > 0 {
-				targetClusters = append(targetClusters, flags.Clusters...)
-			} else {
-				configFiles, err := ioutil.ReadDir(defaults.KindConfigDir)
-				if err != nil {
-					log.Fatal(err)
-				}
-				for _, configFile := range configFiles {
-					clName := strings.FieldsFunc(configFile.Name(), func(r rune) bool { return strings.ContainsRune(" -.", r) })[2]
-					targetClusters = append(targetClusters, clName)
-				}
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
 			}
 
 			var wg sync.WaitGroup
@@ -73,12 +64,17 @@ func DeployNetshootCommand(box *packr.Box) *cobra.Command {
 						log.Fatalf("%s %s", clName, err)
 					}
 
-					err = deploy.Resources(clName, clientSet, netshootDeploymentFile.String(), "Netshoot")
+					dynamicClient, mapper, err := cluster.GetDynamicClient(clName)
+					if err != nil {
+						log.Fatalf("%s %s", clName, err)
+					}
+
+					err = deploy.Resources(clName, dynamicClient, mapper, netshootDeploymentFile.String(), "Netshoot")
 					if err != nil {
 						log.Fatalf("%s %s", clName, err)
 					}
 
-					err = wait.ForDaemonSetReady(clName, clientSet, "default", selector)
+					err = wait.ForDaemonSetReady(clName, clientSet, dynamicClient, mapper, "default", selector, flags.Timeout)
 					if err != nil {
 						log.Fatalf("%s %s", clName, err)
 					}
@@ -91,6 +87,7 @@ func DeployNetshootCommand(box *packr.Box) *cobra.Command {
 	}
 	cmd.Flags().BoolVar(&flags.HostNetwork, "host-network", false, "deploy the pods in host network mode.")
 	cmd.Flags().BoolVarP(&flags.Debug, "debug", "v", false, "set log level to debug")
+	cmd.Flags().DurationVar(&flags.Timeout, "timeout", defaults.WaitDurationResources, "how long to wait for the pods to be ready")
 	cmd.Flags().StringSliceVarP(&flags.Clusters, "clusters", "c", []string{}, "comma separated list of cluster names to deploy to. eg: cl1,cl6,cl3")
 	return cmd
 }
@@ -1,15 +1,14 @@
 package nginx
 
 import (
-	"io/ioutil"
-	"strings"
 	"sync"
+	"time"
 
 	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/dimaunx/armada/pkg/defaults"
 	"github.com/dimaunx/armada/pkg/deploy"
 	"github.com/dimaunx/armada/pkg/wait"
 
-	"github.com/dimaunx/armada/pkg/defaults"
 	"github.com/gobuffalo/packr/v2"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -19,6 +18,7 @@ import (
 type NginxDeployFlagpole struct {
 	Clusters []string
 	Debug    bool
+	Timeout  time.Duration
 }
 
 // DeployNginxDemoCommand returns a new cobra.Command under deploy command for armada
@@ -40,18 +40,9 @@ func DeployNginxDemoCommand(box *packr.Box) *cobra.Command {
 				log.Error(err)
 			}
 
-			var targetClusters []string
-			if len(flags.Clusters) > 0 {
-				targetClusters = append(targetClusters, flags.Clusters...)
-			} else {
-				configFiles, err := ioutil.ReadDir(defaults.KindConfigDir)
-				if err != nil {
-					log.Fatal(err)
-				}
-				for _, configFile := range configFiles {
-					clName := strings.FieldsFunc(configFile.Name(), func(r rune) bool { return strings.ContainsRune(" -.", r) })[2]
-					targetClusters = append(targetClusters, clName)
-				}
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
 			}
 
 			var wg sync.WaitGroup
@@ -63,12 +54,17 @@ func DeployNginxDemoCommand(box *packr.Box) *cobra.Command {
 						log.Fatalf("%s %s", clName, err)
 					}
 
-					err = deploy.Resources(clName, clientSet, nginxDeploymentFile.String(), "Nginx")
+					dynamicClient, mapper, err := cluster.GetDynamicClient(clName)
+					if err != nil {
+						log.Fatalf("%s %s", clName, err)
+					}
+
+					err = deploy.Resources(clName, dynamicClient, mapper, nginxDeploymentFile.String(), "Nginx")
 					if err != nil {
 						log.Fatalf("%s %s", clName, err)
 					}
 
-					err = wait.ForDaemonSetReady(clName, clientSet, "default", "nginx-demo")
+					err = wait.ForDaemonSetReady(clName, clientSet, dynamicClient, mapper, "default", "nginx-demo", flags.Timeout)
 					if err != nil {
 						log.Fatalf("%s %s", clName, err)
 					}
@@ -81,5 +77,6 @@ func DeployNginxDemoCommand(box *packr.Box) *cobra.Command {
 	}
 	cmd.Flags().StringSliceVarP(&flags.Clusters, "clusters", "c", []string{}, "comma separated list of cluster names to deploy to. eg: cl1,cl6,cl3")
 	cmd.Flags().BoolVarP(&flags.Debug, "debug", "v", false, "set log level to debug")
+	cmd.Flags().DurationVar(&flags.Timeout, "timeout", defaults.WaitDurationResources, "how long to wait for the pods to be ready")
 	return cmd
 }
@@ -1,11 +1,7 @@
 package cluster
 
 import (
-	"io/ioutil"
-	"strings"
-
 	"github.com/dimaunx/armada/pkg/cluster"
-	"github.com/dimaunx/armada/pkg/defaults"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	kind "sigs.k8s.io/kind/pkg/cluster"
@@ -26,18 +22,9 @@ func DestroyClustersCommand(provider *kind.Provider) *cobra.Command {
 		Long:  "Destroys clusters",
 		RunE: func(cmd *cobra.Command, args []string) error {
 
-			var targetClusters []string
-			if len(flags.Clusters) > 0 {
-				targetClusters = append(targetClusters, flags.Clusters...)
-			} else {
-				configFiles, err := ioutil.ReadDir(defaults.KindConfigDir)
-				if err != nil {
-					log.Fatal(err)
-				}
-				for _, configFile := range configFiles {
-					clName := strings.FieldsFunc(configFile.Name(), func(r rune) bool { return strings.ContainsRune(" -.", r) })[2]
-					targetClusters = append(targetClusters, clName)
-				}
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
 			}
 
 			for _, clName := range targetClusters {
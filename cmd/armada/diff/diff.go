@@ -0,0 +1,113 @@
+package diff
+
+import (
+	"os"
+
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/dimaunx/armada/pkg/defaults"
+	"github.com/dimaunx/armada/pkg/deploy"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// defaultDiffProgram is used when ARMADA_EXTERNAL_DIFF is not set
+const defaultDiffProgram = "diff -u -N"
+
+// DiffFlagpole is a list of cli flags for diff command
+type DiffFlagpole struct {
+	// Clusters is a list of cluster names to diff, all clusters if empty
+	Clusters []string
+
+	// Files is a list of manifest files or directories to diff instead of the rendered cluster manifests
+	Files []string
+
+	// RenderDir is the directory with the rendered cluster manifests
+	RenderDir string
+
+	// Debug sets log level to debug
+	Debug bool
+}
+
+// DiffCmd returns a new cobra.Command under root command for armada
+func DiffCmd() *cobra.Command {
+	flags := &DiffFlagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "diff",
+		Short: "Show the differences between live objects and manifests",
+		Long: "Show the differences between the live objects of the clusters and the manifests rendered for them by create clusters, " +
+			"or the manifests from --filename. The diff program can be set with ARMADA_EXTERNAL_DIFF (default \"" + defaultDiffProgram + "\")",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if flags.Debug {
+				log.SetLevel(log.DebugLevel)
+			}
+
+			renderDir := flags.RenderDir
+			if renderDir == "" {
+				renderDir = defaults.HomePath(defaults.RenderDir)
+			}
+
+			program := os.Getenv("ARMADA_EXTERNAL_DIFF")
+			if program == "" {
+				program = defaultDiffProgram
+			}
+
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			for _, clName := range targetClusters {
+				if err := DiffCluster(clName, flags.Files, renderDir, program); err != nil {
+					log.Fatalf("%s %s", clName, err)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&flags.Clusters, "clusters", "c", []string{}, "comma separated list of cluster names to diff. eg: cl1,cl6,cl3")
+	cmd.Flags().StringSliceVarP(&flags.Files, "filename", "f", []string{}, "manifest files or directories to diff instead of the rendered cluster manifests")
+	cmd.Flags().StringVar(&flags.RenderDir, "render-dir", "", "directory with the rendered cluster manifests (default <armada home>/render)")
+	cmd.Flags().BoolVarP(&flags.Debug, "debug", "v", false, "set log level to debug")
+	return cmd
+}
+
+// DiffCluster writes the differences between the live objects of the cluster and the manifests to stdout
+func DiffCluster(clName string, files []string, renderDir, program string) error {
+	var manifests []deploy.ManifestFile
+	var err error
+	if len(files) > 0 {
+		manifests, err = deploy.ReadManifestFiles(files...)
+	} else {
+		manifests, err = cluster.ReadRenderedManifests(renderDir, clName)
+		if os.IsNotExist(errors.Cause(err)) {
+			log.Warnf("No rendered manifests found for %s in %s.", clName, renderDir)
+			return nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	dynamicClient, mapper, err := cluster.GetDynamicClient(clName)
+	if err != nil {
+		return err
+	}
+
+	var diffs []deploy.ObjectDiff
+	for _, manifest := range manifests {
+		manifestDiffs, err := deploy.Diff(dynamicClient, mapper, manifest.Content)
+		if err != nil {
+			return errors.Wrapf(err, "diffing %s", manifest.Path)
+		}
+		diffs = append(diffs, manifestDiffs...)
+	}
+
+	if len(diffs) == 0 {
+		log.Infof("✔ %s is up to date.", clName)
+		return nil
+	}
+	return deploy.WriteDiff(os.Stdout, clName, diffs, program)
+}
@@ -1,11 +1,9 @@
 package logs
 
 import (
-	"io/ioutil"
 	"os"
-	"path/filepath"
-	"strings"
 
+	"github.com/dimaunx/armada/pkg/cluster"
 	"github.com/dimaunx/armada/pkg/defaults"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -27,24 +25,17 @@ func ExportLogsCommand(provider *kind.Provider) *cobra.Command {
 		Long:  "Export kind cluster logs",
 		RunE: func(cmd *cobra.Command, args []string) error {
 
-			// remove existing before exporting
-			_ = os.RemoveAll(filepath.Join(defaults.KindLogsDir, defaults.KindLogsDir))
-
-			var targetClusters []string
-			if len(flags.Clusters) > 0 {
-				targetClusters = append(targetClusters, flags.Clusters...)
-			} else {
-				configFiles, err := ioutil.ReadDir(defaults.KindConfigDir)
-				if err != nil {
-					log.Fatal(err)
-				}
-				for _, configFile := range configFiles {
-					clName := strings.FieldsFunc(configFile.Name(), func(r rune) bool { return strings.ContainsRune(" -.", r) })[2]
-					targetClusters = append(targetClusters, clName)
-				}
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
 			}
 			for _, clName := range targetClusters {
-				err := provider.CollectLogs(clName, filepath.Join(defaults.KindLogsDir, clName))
+				logsDir := defaults.HomePath(defaults.KindLogsDir, clName)
+
+				// remove existing before exporting
+				_ = os.RemoveAll(logsDir)
+
+				err := provider.CollectLogs(clName, logsDir)
 				if err != nil {
 					log.Fatalf("%s: %v", clName, err)
 				}
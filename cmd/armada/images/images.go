@@ -0,0 +1,22 @@
+package images
+
+import (
+	"github.com/dimaunx/armada/cmd/armada/images/node"
+	"github.com/dimaunx/armada/cmd/armada/images/status"
+	"github.com/gobuffalo/packr/v2"
+	"github.com/spf13/cobra"
+	kind "sigs.k8s.io/kind/pkg/cluster"
+)
+
+// ImagesCmd returns a new cobra.Command under root command for armada
+func ImagesCmd(box *packr.Box, provider *kind.Provider) *cobra.Command {
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "images",
+		Short: "Show images used by the clusters",
+		Long:  "Show images used by the clusters",
+	}
+	cmd.AddCommand(node.NodeCmd(box))
+	cmd.AddCommand(status.ImagesStatusCommand(provider))
+	return cmd
+}
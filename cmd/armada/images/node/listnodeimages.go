@@ -0,0 +1,72 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dimaunx/armada/pkg/image"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/gobuffalo/packr/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	kinddefaults "sigs.k8s.io/kind/pkg/apis/config/defaults"
+)
+
+// ListNodeImagesFlagpole is a list of cli flags for list node images command
+type ListNodeImagesFlagpole struct {
+	Debug bool
+}
+
+// ListNodeImagesCommand returns a new cobra.Command under node images command for armada
+func ListNodeImagesCommand(box *packr.Box) *cobra.Command {
+	flags := &ListNodeImagesFlagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "list",
+		Short: "List kind node images",
+		Long:  "List kind node images supported by the bundled kind version, their pinned digests and local presence",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if flags.Debug {
+				log.SetLevel(log.DebugLevel)
+			}
+
+			catalog, err := image.LoadNodeImageCatalog(box)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			ctx := context.Background()
+			dockerCli, err := dockerclient.NewEnvClient()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "KUBERNETES\tIMAGE\tDIGEST\tLOCAL\tDEFAULT")
+			for i := range catalog.Images {
+				img := &catalog.Images[i]
+				local, err := image.GetNodeImagePresence(ctx, dockerCli, img)
+				if err != nil {
+					log.Debugf("Unable to check local image %s: %v.", img.Image, err)
+				}
+
+				digest := img.Digest
+				if digest == "" {
+					digest = "-"
+				}
+
+				var isDefault string
+				if img.Reference() == kinddefaults.Image {
+					isDefault = "*"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", img.KubernetesVersion, img.Image, digest, local, isDefault)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().BoolVarP(&flags.Debug, "debug", "v", false, "set log level to debug")
+	return cmd
+}
@@ -0,0 +1,18 @@
+package node
+
+import (
+	"github.com/gobuffalo/packr/v2"
+	"github.com/spf13/cobra"
+)
+
+// NodeCmd returns a new cobra.Command under images command for armada
+func NodeCmd(box *packr.Box) *cobra.Command {
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "node",
+		Short: "Show kind node images",
+		Long:  "Show kind node images supported by the bundled kind version",
+	}
+	cmd.AddCommand(ListNodeImagesCommand(box))
+	return cmd
+}
@@ -0,0 +1,77 @@
+package status
+
+import (
+	"context"
+	"os"
+
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/dimaunx/armada/pkg/image"
+	dockerclient "github.com/docker/docker/client"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	kind "sigs.k8s.io/kind/pkg/cluster"
+)
+
+// ImagesStatusFlagpole is a list of cli flags for images status command
+type ImagesStatusFlagpole struct {
+	// Clusters is a list of cluster names to check, all clusters if empty
+	Clusters []string
+
+	// Images is a list of images to check
+	Images []string
+
+	// Debug sets log level to debug
+	Debug bool
+}
+
+// ImagesStatusCommand returns a new cobra.Command under images command for armada
+func ImagesStatusCommand(provider *kind.Provider) *cobra.Command {
+	flags := &ImagesStatusFlagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "status",
+		Short: "Show the images presence on the cluster nodes",
+		Long: "Show a matrix of the cluster nodes and the images. An image is current if the node has the local docker " +
+			"image id, stale if it has another id, missing if the node does not have it and present if it is not found locally.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if flags.Debug {
+				log.SetLevel(log.DebugLevel)
+			}
+
+			if len(flags.Images) == 0 {
+				log.Fatal("at least one image is required, use --images.")
+			}
+
+			ctx := context.Background()
+			dockerCli, err := dockerclient.NewEnvClient()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			var images []image.Image
+			for _, imageName := range flags.Images {
+				id, err := image.GetLocalID(ctx, dockerCli, imageName)
+				if err != nil {
+					log.Warnf("%v The image ids on the nodes can not be compared.", err)
+				}
+				images = append(images, image.Image{Name: imageName, ID: id})
+			}
+
+			statuses, err := image.GetNodeImageStatuses(provider, images, targetClusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+			return image.WriteStatusMatrix(os.Stdout, images, statuses)
+		},
+	}
+	cmd.Flags().StringSliceVarP(&flags.Clusters, "clusters", "c", []string{}, "comma separated list of cluster names to check. eg: cl1,cl6,cl3")
+	cmd.Flags().StringSliceVarP(&flags.Images, "images", "i", []string{}, "comma separated list of images to check.")
+	cmd.Flags().BoolVarP(&flags.Debug, "debug", "v", false, "set log level to debug")
+	return cmd
+}
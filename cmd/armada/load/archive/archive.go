@@ -0,0 +1,105 @@
+package archive
+
+import (
+	"os"
+	"strings"
+
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/dimaunx/armada/pkg/image"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	kind "sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+)
+
+// LoadArchiveFlagpole is a list of cli flags for load archive command
+type LoadArchiveFlagpole struct {
+	Clusters    []string
+	Files       []string
+	Concurrency int
+	Debug       bool
+}
+
+// LoadArchiveCommand returns a new cobra.Command under load command for armada
+func LoadArchiveCommand(provider *kind.Provider) *cobra.Command {
+	flags := &LoadArchiveFlagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "archive",
+		Short: "Load image archives in to the cluster",
+		Long: "Load docker save archives and OCI image layouts, directories or tars, in to the cluster without a docker daemon. " +
+			"The image ids and tags are read from the archive and nodes that already have all of them are skipped.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if flags.Debug {
+				log.SetLevel(log.DebugLevel)
+			}
+
+			if len(flags.Files) == 0 {
+				log.Fatal("at least one archive is required, use --file.")
+			}
+
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			for _, file := range flags.Files {
+				err = Archive(provider, file, targetClusters, flags.Concurrency)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&flags.Clusters, "clusters", "c", []string{}, "comma separated list of cluster names to load the archive in to.")
+	cmd.Flags().StringSliceVarP(&flags.Files, "file", "f", []string{}, "docker save archive or OCI image layout to load, can be repeated.")
+	cmd.Flags().IntVar(&flags.Concurrency, "concurrency", image.DefaultLoadConcurrency, "number of nodes the archive is streamed to at once.")
+	cmd.Flags().BoolVarP(&flags.Debug, "debug", "v", false, "set log level to debug")
+	return cmd
+}
+
+// Archive loads the archive to the nodes of the clusters that miss any of its images
+func Archive(provider *kind.Provider, path string, clusters []string, concurrency int) error {
+	images, err := image.ArchiveImages(path)
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		return errors.Errorf("no images found in %s", path)
+	}
+
+	groups, err := image.GroupNodesByMissingImages(provider, images, clusters)
+	if err != nil {
+		return err
+	}
+
+	// the archive is loaded as a whole, so every node missing any of its images gets all of them
+	var nodeList []nodes.Node
+	for _, group := range groups {
+		nodeList = append(nodeList, group.Nodes...)
+	}
+	label := strings.Join(image.Names(images), ", ")
+	if len(nodeList) == 0 {
+		log.Infof("✔ images: %s are already present on all nodes", label)
+		return nil
+	}
+
+	archivePath := path
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		archivePath, err = image.TarDir(path)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(archivePath)
+	}
+
+	log.Infof("loading images: %s from %s to nodes: %s ...", label, path, nodeList)
+	return image.LoadArchive(archivePath, label, nodeList, concurrency)
+}
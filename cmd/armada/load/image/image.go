@@ -2,26 +2,27 @@ package image
 
 import (
 	"context"
-	"io/ioutil"
 	"os"
-	"path/filepath"
-	"strings"
-	"sync"
+	"os/signal"
+	"syscall"
 
-	"github.com/dimaunx/armada/pkg/defaults"
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/dimaunx/armada/pkg/deploy"
 	"github.com/dimaunx/armada/pkg/image"
 	dockerclient "github.com/docker/docker/client"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	kind "sigs.k8s.io/kind/pkg/cluster"
-	"sigs.k8s.io/kind/pkg/cluster/nodes"
 )
 
 // LoadImagesFlagpole is a list of cli flags for export logs command
 type LoadImagesFlagpole struct {
-	Clusters []string
-	Images   []string
-	Debug    bool
+	Clusters    []string
+	Images      []string
+	Concurrency int
+	Watch       bool
+	Debug       bool
 }
 
 // LoadImageCommand returns a new cobra.Command under load command for armada
@@ -31,7 +32,9 @@ func LoadImageCommand(provider *kind.Provider) *cobra.Command {
 		Args:  cobra.NoArgs,
 		Use:   "docker-images",
 		Short: "Load docker images in to the cluster",
-		Long:  "Load docker images in to the cluster",
+		Long: "Load docker images in to the cluster. The images missing on the nodes are saved to a single archive cached " +
+			"by the image names and ids under the armada home and streamed to the nodes in parallel. With --watch the images are " +
+			"reloaded whenever they are rebuilt and the deployments, daemon sets and stateful sets using them are restarted.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 
 			if flags.Debug {
@@ -44,51 +47,37 @@ func LoadImageCommand(provider *kind.Provider) *cobra.Command {
 				log.Fatal(err)
 			}
 
-			var targetClusters []string
-			if len(flags.Clusters) > 0 {
-				targetClusters = append(targetClusters, flags.Clusters...)
-			} else {
-				configFiles, err := ioutil.ReadDir(defaults.KindConfigDir)
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if len(targetClusters) > 0 {
+				images, err := image.GetLocalImages(ctx, dockerCli, flags.Images)
 				if err != nil {
 					log.Fatal(err)
 				}
-				for _, configFile := range configFiles {
-					clName := strings.FieldsFunc(configFile.Name(), func(r rune) bool { return strings.ContainsRune(" -.", r) })[2]
-					targetClusters = append(targetClusters, clName)
+
+				err = image.LoadImages(ctx, dockerCli, provider, images, targetClusters, flags.Concurrency)
+				if err != nil {
+					log.Fatal(err)
 				}
-			}
 
-			if len(targetClusters) > 0 {
-				for _, imageName := range flags.Images {
-					localImageID, err := image.GetLocalID(ctx, dockerCli, imageName)
+				if flags.Watch {
+					ctx, cancel := context.WithCancel(ctx)
+					signals := make(chan os.Signal, 1)
+					signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+					go func() {
+						<-signals
+						cancel()
+					}()
+
+					err = image.Watch(ctx, dockerCli, images, func(img image.Image) error {
+						return Reload(ctx, dockerCli, provider, img, targetClusters, flags.Concurrency)
+					})
 					if err != nil {
 						log.Fatal(err)
 					}
-					selectedNodes, err := image.GetNodesWithout(provider, imageName, localImageID, targetClusters)
-					if err != nil {
-						log.Error(err)
-					}
-					if len(selectedNodes) > 0 {
-						imageTarPath, err := image.Save(ctx, dockerCli, imageName)
-						if err != nil {
-							log.Fatal(err)
-						}
-						defer os.RemoveAll(filepath.Dir(imageTarPath))
-
-						log.Infof("loading image: %s to nodes: %s ...", imageName, selectedNodes)
-						var wg sync.WaitGroup
-						wg.Add(len(selectedNodes))
-						for _, node := range selectedNodes {
-							go func(node nodes.Node) {
-								err = image.LoadToNode(imageTarPath, imageName, node, &wg)
-								if err != nil {
-									defer wg.Done()
-									log.Fatal(err)
-								}
-							}(node)
-						}
-						wg.Wait()
-					}
 				}
 			}
 			return nil
@@ -96,6 +85,32 @@ func LoadImageCommand(provider *kind.Provider) *cobra.Command {
 	}
 	cmd.Flags().StringSliceVarP(&flags.Clusters, "clusters", "c", []string{}, "comma separated list of cluster names to load the image in to.")
 	cmd.Flags().StringSliceVarP(&flags.Images, "images", "i", []string{}, "comma separated list images to load.")
+	cmd.Flags().IntVar(&flags.Concurrency, "concurrency", image.DefaultLoadConcurrency, "number of nodes the images are streamed to at once.")
+	cmd.Flags().BoolVar(&flags.Watch, "watch", false, "keep running, reload the images when they are rebuilt and restart the workloads using them.")
 	cmd.Flags().BoolVarP(&flags.Debug, "debug", "v", false, "set log level to debug")
 	return cmd
 }
+
+// Reload loads the rebuilt image to the nodes of the clusters and restarts the workloads using it
+func Reload(ctx context.Context, dockerCli *dockerclient.Client, provider *kind.Provider, img image.Image, clusters []string, concurrency int) error {
+	log.Infof("image %s was rebuilt as %s, reloading ...", img.Name, img.ID)
+	err := image.LoadImages(ctx, dockerCli, provider, []image.Image{img}, clusters, concurrency)
+	if err != nil {
+		return err
+	}
+
+	for _, clName := range clusters {
+		clientSet, err := cluster.GetClientSet(clName)
+		if err != nil {
+			return err
+		}
+		restarted, err := deploy.RestartWorkloadsUsing(clName, clientSet, img.Name)
+		if err != nil {
+			return errors.Wrapf(err, "restarting workloads using %s in %s", img.Name, clName)
+		}
+		if len(restarted) == 0 {
+			log.Infof("no workloads use image %s in %s", img.Name, clName)
+		}
+	}
+	return nil
+}
@@ -1,19 +1,100 @@
 package load
 
 import (
+	"context"
+
+	"github.com/dimaunx/armada/cmd/armada/load/archive"
 	"github.com/dimaunx/armada/cmd/armada/load/image"
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/dimaunx/armada/pkg/deploy"
+	pkgimage "github.com/dimaunx/armada/pkg/image"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	kind "sigs.k8s.io/kind/pkg/cluster"
 )
 
+// LoadFlagpole is a list of cli flags for load command
+type LoadFlagpole struct {
+	Clusters      []string
+	FromManifests []string
+	Concurrency   int
+	Debug         bool
+}
+
 // LoadCmd returns a new cobra.Command under root command for armada
 func LoadCmd(provider *kind.Provider) *cobra.Command {
+	flags := &LoadFlagpole{}
 	cmd := &cobra.Command{
 		Args:  cobra.NoArgs,
 		Use:   "load",
-		Short: "Load resources in to hte cluster",
-		Long:  "Load resources in to hte cluster",
+		Short: "Load resources in to the cluster",
+		Long: "Load resources in to the cluster. With --from-manifests the container images of the manifests that " +
+			"exist locally are loaded, the others are left for the nodes to pull.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(flags.FromManifests) == 0 {
+				return cmd.Help()
+			}
+
+			if flags.Debug {
+				log.SetLevel(log.DebugLevel)
+			}
+
+			ctx := context.Background()
+			dockerCli, err := dockerclient.NewEnvClient()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if len(targetClusters) > 0 {
+				images, err := ManifestImages(flags.FromManifests...)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				err = pkgimage.LoadLocalImages(ctx, dockerCli, provider, images, targetClusters, flags.Concurrency)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+			return nil
+		},
 	}
+	cmd.Flags().StringSliceVarP(&flags.Clusters, "clusters", "c", []string{}, "comma separated list of cluster names to load the images in to.")
+	cmd.Flags().StringSliceVar(&flags.FromManifests, "from-manifests", []string{}, "manifest files or directories to load the container images of, can be repeated.")
+	cmd.Flags().IntVar(&flags.Concurrency, "concurrency", pkgimage.DefaultLoadConcurrency, "number of nodes the images are streamed to at once.")
+	cmd.Flags().BoolVarP(&flags.Debug, "debug", "v", false, "set log level to debug")
 	cmd.AddCommand(image.LoadImageCommand(provider))
+	cmd.AddCommand(archive.LoadArchiveCommand(provider))
 	return cmd
 }
+
+// ManifestImages returns the container images of the manifest files and directories
+func ManifestImages(paths ...string) ([]string, error) {
+	files, err := deploy.ReadManifestFiles(paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	var images []string
+	seen := map[string]bool{}
+	for _, file := range files {
+		objects, err := deploy.Decode(file.Content)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding %s", file.Path)
+		}
+		for _, img := range deploy.ContainerImages(objects) {
+			if !seen[img] {
+				seen[img] = true
+				images = append(images, img)
+			}
+		}
+	}
+	return images, nil
+}
@@ -0,0 +1,164 @@
+package migrate
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/dimaunx/armada/pkg/defaults"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// MigrateFlagpole is a list of cli flags for migrate command
+type MigrateFlagpole struct {
+	// Source is the legacy output directory
+	Source string
+
+	// Force overwrites the files that already exist in armada home
+	Force bool
+}
+
+// stateDirs are the directories moved from the legacy output directory
+var stateDirs = []string{
+	defaults.KindConfigDir,
+	defaults.LocalKubeConfigDir,
+	defaults.ContainerKubeConfigDir,
+	defaults.KindLogsDir,
+}
+
+// MigrateCmd returns a new cobra.Command under root command for armada
+func MigrateCmd() *cobra.Command {
+	flags := &MigrateFlagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "migrate",
+		Short: "Migrate legacy output directory to armada home",
+		Long:  "Moves clusters state from the legacy ./output directory to armada home",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			skipped, err := Migrate(flags.Source, flags.Force)
+			if err != nil {
+				return err
+			}
+
+			if len(skipped) > 0 {
+				log.Warnf("%d files already exist in %s and were left in %s, use --force to overwrite them.", len(skipped), defaults.Home(), flags.Source)
+				return nil
+			}
+			log.Infof("✔ State from %s was migrated to %s.", flags.Source, defaults.Home())
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&flags.Source, "source", "s", defaults.LegacyOutputDir, "legacy output directory to migrate")
+	cmd.Flags().BoolVar(&flags.Force, "force", false, "overwrite the files that already exist in armada home")
+	return cmd
+}
+
+// Migrate moves the clusters state from the legacy output directory to armada home and returns the files that were
+// left in source because they already exist in armada home, unless force is set
+func Migrate(source string, force bool) ([]string, error) {
+	source, err := filepath.Abs(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if source == defaults.Home() {
+		log.Infof("✔ %s is already armada home.", source)
+		return nil, nil
+	}
+
+	var skipped []string
+	for _, dir := range stateDirs {
+		dirSkipped, err := moveTree(filepath.Join(source, dir), defaults.HomePath(dir), force)
+		if err != nil {
+			return skipped, err
+		}
+		skipped = append(skipped, dirSkipped...)
+	}
+	// remove the legacy directories if nothing is left in them
+	_ = os.Remove(filepath.Join(source, filepath.Dir(defaults.LocalKubeConfigDir)))
+	_ = os.Remove(source)
+	return skipped, nil
+}
+
+// LegacyStateExists returns true if a legacy output directory with clusters state exists in the current directory
+func LegacyStateExists() bool {
+	source, err := filepath.Abs(defaults.LegacyOutputDir)
+	if err != nil || source == defaults.Home() {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(source, defaults.KindConfigDir))
+	return err == nil
+}
+
+// moveTree moves all the files from src directory to dst directory and returns the files left in src because they
+// already exist in dst. src is removed if all the files were moved.
+func moveTree(src, dst string, force bool) ([]string, error) {
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var skipped []string
+	var dirs []string
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			dirs = append(dirs, path)
+			return os.MkdirAll(target, os.ModePerm)
+		}
+
+		if _, err := os.Stat(target); err == nil && !force {
+			log.Warnf("%s already exists, skipping %s. Use --force to overwrite.", target, path)
+			skipped = append(skipped, path)
+			return nil
+		}
+
+		log.Debugf("Moving %s to %s.", path, target)
+		if err := os.Rename(path, target); err == nil {
+			return nil
+		}
+		if err := copyFile(path, target, info.Mode()); err != nil {
+			return err
+		}
+		return os.Remove(path)
+	})
+	if err != nil {
+		return skipped, errors.Wrapf(err, "failed to migrate %s", src)
+	}
+
+	// the emptied directories are removed deepest first, the ones with skipped files stay
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(dirs[i])
+	}
+	return skipped, nil
+}
+
+// copyFile copies a file, used if rename fails between different devices
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
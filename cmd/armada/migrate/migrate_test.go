@@ -0,0 +1,89 @@
+package migrate_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dimaunx/armada/cmd/armada/migrate"
+	"github.com/dimaunx/armada/pkg/defaults"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// writeFile writes the file creating its directory
+func writeFile(path, content string) {
+	Ω(os.MkdirAll(filepath.Dir(path), 0755)).ShouldNot(HaveOccurred())
+	Ω(ioutil.WriteFile(path, []byte(content), 0644)).ShouldNot(HaveOccurred())
+}
+
+// readFile returns the file content
+func readFile(path string) string {
+	content, err := ioutil.ReadFile(path)
+	Ω(err).ShouldNot(HaveOccurred())
+	return string(content)
+}
+
+func TestMigrate(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Migrate test suite")
+}
+
+var _ = Describe("Migrate tests", func() {
+	var dir, source, home string
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "armada-migrate")
+		Ω(err).ShouldNot(HaveOccurred())
+		source = filepath.Join(dir, defaults.LegacyOutputDir)
+		home = filepath.Join(dir, "home")
+		Ω(defaults.SetHome(home)).ShouldNot(HaveOccurred())
+
+		writeFile(filepath.Join(source, defaults.KindConfigDir, "cluster1-config.yaml"), "legacy1")
+		writeFile(filepath.Join(source, defaults.KindConfigDir, "cluster2-config.yaml"), "legacy2")
+		writeFile(filepath.Join(source, defaults.LocalKubeConfigDir, "kind-config-cluster1"), "kubeconfig1")
+		writeFile(filepath.Join(home, defaults.KindConfigDir, "cluster2-config.yaml"), "home2")
+	})
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("Should leave the files that already exist in armada home in place", func() {
+		skipped, err := migrate.Migrate(source, false)
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(skipped).Should(Equal([]string{filepath.Join(source, defaults.KindConfigDir, "cluster2-config.yaml")}))
+
+		Expect(readFile(defaults.HomePath(defaults.KindConfigDir, "cluster1-config.yaml"))).Should(Equal("legacy1"))
+		Expect(readFile(defaults.HomePath(defaults.LocalKubeConfigDir, "kind-config-cluster1"))).Should(Equal("kubeconfig1"))
+		Expect(readFile(defaults.HomePath(defaults.KindConfigDir, "cluster2-config.yaml"))).Should(Equal("home2"))
+		Expect(readFile(filepath.Join(source, defaults.KindConfigDir, "cluster2-config.yaml"))).Should(Equal("legacy2"))
+
+		_, err = os.Stat(filepath.Join(source, defaults.KindConfigDir, "cluster1-config.yaml"))
+		Expect(os.IsNotExist(err)).Should(BeTrue())
+		_, err = os.Stat(filepath.Join(source, defaults.LocalKubeConfigDir))
+		Expect(os.IsNotExist(err)).Should(BeTrue())
+	})
+	It("Should overwrite the existing files with force", func() {
+		skipped, err := migrate.Migrate(source, true)
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(skipped).Should(BeEmpty())
+		Expect(readFile(defaults.HomePath(defaults.KindConfigDir, "cluster2-config.yaml"))).Should(Equal("legacy2"))
+
+		_, err = os.Stat(source)
+		Expect(os.IsNotExist(err)).Should(BeTrue())
+	})
+	It("Should do nothing if the state was already migrated", func() {
+		_, err := migrate.Migrate(source, true)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		skipped, err := migrate.Migrate(source, false)
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(skipped).Should(BeEmpty())
+
+		skipped, err = migrate.Migrate(home, false)
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(skipped).Should(BeEmpty())
+		Expect(readFile(defaults.HomePath(defaults.KindConfigDir, "cluster1-config.yaml"))).Should(Equal("legacy1"))
+	})
+})
@@ -6,9 +6,19 @@ import (
 	"github.com/dimaunx/armada/cmd/armada/create"
 	"github.com/dimaunx/armada/cmd/armada/deploy"
 	"github.com/dimaunx/armada/cmd/armada/destroy"
+	"github.com/dimaunx/armada/cmd/armada/diff"
 	"github.com/dimaunx/armada/cmd/armada/export"
+	"github.com/dimaunx/armada/cmd/armada/images"
 	"github.com/dimaunx/armada/cmd/armada/load"
+	"github.com/dimaunx/armada/cmd/armada/migrate"
+	"github.com/dimaunx/armada/cmd/armada/status"
+	templatescmd "github.com/dimaunx/armada/cmd/armada/templates"
+	"github.com/dimaunx/armada/cmd/armada/undeploy"
 	"github.com/dimaunx/armada/cmd/armada/version"
+	"github.com/dimaunx/armada/cmd/armada/wait"
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/dimaunx/armada/pkg/defaults"
+	"github.com/dimaunx/armada/pkg/templates"
 	"github.com/gobuffalo/packr/v2"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -22,14 +32,66 @@ var (
 	Version string
 )
 
+// RootFlagpole is a list of global cli flags for armada
+type RootFlagpole struct {
+	// KubeConfigMode selects local, container or auto detected kubeconfig files
+	KubeConfigMode string
+
+	// Home is the armada home directory
+	Home string
+
+	// TemplatesDir is a directory with user templates shadowing the embedded ones
+	TemplatesDir string
+}
+
 // NewRootCmd returns a new cobra.Command implementing the root command for armada
 func NewRootCmd() *cobra.Command {
+	flags := &RootFlagpole{}
+	box := packr.New("configs", "../../configs")
 	cmd := &cobra.Command{
 		Args:  cobra.NoArgs,
 		Use:   "armada",
 		Short: "Armada is a tool for e2e environment creation for submariner-io org",
 		Long:  "Creates multiple kind clusters and e2e environments",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			home := flags.Home
+			if home == "" {
+				home = os.Getenv("ARMADA_HOME")
+			}
+			if home != "" {
+				if err := defaults.SetHome(home); err != nil {
+					return err
+				}
+			}
+			log.Debugf("Armada home: %s.", defaults.Home())
+
+			if cmd.Name() != "migrate" && migrate.LegacyStateExists() {
+				log.Warnf("Found clusters state in ./%s, armada home is %s now. Run 'armada migrate' to move it.", defaults.LegacyOutputDir, defaults.Home())
+			}
+
+			templatesDir := flags.TemplatesDir
+			if templatesDir == "" {
+				templatesDir = os.Getenv("ARMADA_TEMPLATES")
+			}
+			if templatesDir != "" {
+				if err := templates.Overlay(box, templatesDir); err != nil {
+					return err
+				}
+			}
+
+			kubeConfigMode := flags.KubeConfigMode
+			if kubeConfigMode == "" {
+				kubeConfigMode = os.Getenv("ARMADA_KUBECONFIG_MODE")
+			}
+			if kubeConfigMode == "" {
+				kubeConfigMode = cluster.KubeConfigModeAuto
+			}
+			return cluster.SetKubeConfigMode(kubeConfigMode)
+		},
 	}
+	cmd.PersistentFlags().StringVar(&flags.Home, "home", "", "armada home directory for clusters state. Env ARMADA_HOME (default $XDG_DATA_HOME/armada or ~/.local/share/armada)")
+	cmd.PersistentFlags().StringVar(&flags.TemplatesDir, "templates-dir", "", "directory with templates shadowing or adding to the embedded ones. Env ARMADA_TEMPLATES")
+	cmd.PersistentFlags().StringVar(&flags.KubeConfigMode, "kubeconfig-mode", "", "kubeconfig files to use: local, container or auto. Env ARMADA_KUBECONFIG_MODE (default auto)")
 
 	customFormatter := new(log.TextFormatter)
 	customFormatter.TimestampFormat = "2006-01-02 15:04:05"
@@ -40,14 +102,19 @@ func NewRootCmd() *cobra.Command {
 		kind.ProviderWithLogger(kindcmd.NewLogger()),
 	)
 
-	box := packr.New("configs", "../../configs")
-
 	cmd.AddCommand(create.CreateCmd(provider, box))
 	cmd.AddCommand(destroy.DestroyCmd(provider))
 	cmd.AddCommand(export.ExportCmd(provider))
+	cmd.AddCommand(images.ImagesCmd(box, provider))
 	cmd.AddCommand(load.LoadCmd(provider))
 	cmd.AddCommand(deploy.DeployCmd(box))
+	cmd.AddCommand(diff.DiffCmd())
+	cmd.AddCommand(migrate.MigrateCmd())
+	cmd.AddCommand(status.StatusCmd(provider))
+	cmd.AddCommand(templatescmd.TemplatesCmd(box))
+	cmd.AddCommand(undeploy.UndeployCmd(box))
 	cmd.AddCommand(version.VersionCmd(Version, Build))
+	cmd.AddCommand(wait.WaitCmd())
 	return cmd
 }
 
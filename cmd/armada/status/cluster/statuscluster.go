@@ -0,0 +1,58 @@
+package cluster
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dimaunx/armada/pkg/cluster"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	kind "sigs.k8s.io/kind/pkg/cluster"
+)
+
+// StatusClusterFlagpole is a list of cli flags for status clusters command
+type StatusClusterFlagpole struct {
+	Clusters []string
+}
+
+// StatusClustersCommand returns a new cobra.Command under status command for armada
+func StatusClustersCommand(provider *kind.Provider) *cobra.Command {
+	flags := &StatusClusterFlagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "clusters",
+		Short: "Show clusters status",
+		Long:  "Show the state, nodes, kubernetes version and kube-proxy mode of the clusters",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "NAME\tSTATE\tNODES\tKUBERNETES\tKUBE-PROXY")
+			for _, clName := range targetClusters {
+				status, err := cluster.GetStatus(clName, provider)
+				if err != nil {
+					log.Errorf("%s: %v", clName, err)
+					if status == nil {
+						continue
+					}
+				}
+
+				nodes, version, kubeProxyMode := "-", "-", "-"
+				if status.State == cluster.StateRunning {
+					nodes = fmt.Sprintf("%d/%d", status.ReadyNodes, status.Nodes)
+					version = status.KubernetesVersion
+					kubeProxyMode = status.KubeProxyMode
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", status.Name, status.State, nodes, version, kubeProxyMode)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringSliceVarP(&flags.Clusters, "clusters", "c", []string{}, "comma separated list of cluster names. eg: cl1,cl6,cl3")
+	return cmd
+}
@@ -0,0 +1,19 @@
+package status
+
+import (
+	"github.com/dimaunx/armada/cmd/armada/status/cluster"
+	"github.com/spf13/cobra"
+	kind "sigs.k8s.io/kind/pkg/cluster"
+)
+
+// StatusCmd returns a new cobra.Command under root command for armada
+func StatusCmd(provider *kind.Provider) *cobra.Command {
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "status",
+		Short: "Show e2e environment status",
+		Long:  "Show the status of the clusters",
+	}
+	cmd.AddCommand(cluster.StatusClustersCommand(provider))
+	return cmd
+}
@@ -0,0 +1,34 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dimaunx/armada/pkg/templates"
+	"github.com/gobuffalo/packr/v2"
+	"github.com/spf13/cobra"
+)
+
+// ListTemplatesCommand returns a new cobra.Command under templates command for armada
+func ListTemplatesCommand(box *packr.Box) *cobra.Command {
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "list",
+		Short: "List templates and where they come from",
+		Long:  "List templates and where they come from, embedded in armada or the templates directory",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "TEMPLATE\tORIGIN\tFILE")
+			for _, t := range templates.List(box) {
+				path := t.Path
+				if path == "" {
+					path = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", t.Name, t.Origin, path)
+			}
+			return w.Flush()
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,18 @@
+package templates
+
+import (
+	"github.com/gobuffalo/packr/v2"
+	"github.com/spf13/cobra"
+)
+
+// TemplatesCmd returns a new cobra.Command under root command for armada
+func TemplatesCmd(box *packr.Box) *cobra.Command {
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "templates",
+		Short: "Show the templates and manifests used by armada",
+		Long:  "Show the templates and manifests used by armada",
+	}
+	cmd.AddCommand(ListTemplatesCommand(box))
+	return cmd
+}
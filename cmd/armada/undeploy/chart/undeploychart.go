@@ -0,0 +1,83 @@
+package chart
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/dimaunx/armada/pkg/defaults"
+	"github.com/dimaunx/armada/pkg/helm"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// ChartUndeployFlagpole is a list of cli flags for undeploy chart command
+type ChartUndeployFlagpole struct {
+	// Namespace is the release namespace
+	Namespace string
+
+	// Helm is the helm 3 binary
+	Helm string
+
+	// Timeout is how long helm waits for the release hooks
+	Timeout time.Duration
+
+	// Clusters is a list of cluster names to remove the release from, all clusters if empty
+	Clusters []string
+
+	// Debug sets log level to debug
+	Debug bool
+}
+
+// UndeployChartCommand returns a new cobra.Command under undeploy command for armada
+func UndeployChartCommand() *cobra.Command {
+	flags := &ChartUndeployFlagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Use:   "chart <release>",
+		Short: "Remove a helm 3 chart release",
+		Long:  "Uninstall a helm 3 chart release deployed by deploy chart command from the clusters with the helm binary, which must be installed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if flags.Debug {
+				log.SetLevel(log.DebugLevel)
+			}
+
+			releaseName := args[0]
+			if err := helm.CheckVersion(flags.Helm); err != nil {
+				log.Fatal(err)
+			}
+
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			var wg sync.WaitGroup
+			wg.Add(len(targetClusters))
+			for _, clName := range targetClusters {
+				go func(clName string) {
+					kubeConfigPath, err := cluster.GetKubeConfigPath(clName)
+					if err != nil {
+						log.Fatalf("%s %s", clName, err)
+					}
+
+					err = helm.Uninstall(clName, flags.Helm, kubeConfigPath, releaseName, flags.Namespace, flags.Timeout)
+					if err != nil {
+						log.Fatalf("%s %s", clName, err)
+					}
+					log.Infof("✔ Release %s was removed from %s.", releaseName, clName)
+					wg.Done()
+				}(clName)
+			}
+			wg.Wait()
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&flags.Namespace, "namespace", "n", "default", "release namespace")
+	cmd.Flags().StringVar(&flags.Helm, "helm", "helm", "helm 3 binary")
+	cmd.Flags().DurationVar(&flags.Timeout, "timeout", defaults.WaitDurationResources, "how long helm waits for the release hooks")
+	cmd.Flags().StringSliceVarP(&flags.Clusters, "clusters", "c", []string{}, "comma separated list of cluster names to remove the release from. eg: cl1,cl6,cl3")
+	cmd.Flags().BoolVarP(&flags.Debug, "debug", "v", false, "set log level to debug")
+	return cmd
+}
@@ -0,0 +1,82 @@
+package netshoot
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/dimaunx/armada/pkg/defaults"
+	"github.com/dimaunx/armada/pkg/deploy"
+	"github.com/gobuffalo/packr/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// NetshootUndeployFlagpole is a list of cli flags for undeploy netshoot command
+type NetshootUndeployFlagpole struct {
+	HostNetwork      bool
+	Timeout          time.Duration
+	RemoveFinalizers bool
+	Debug            bool
+	Clusters         []string
+}
+
+// UndeployNetshootCommand returns a new cobra.Command under undeploy command for armada
+func UndeployNetshootCommand(box *packr.Box) *cobra.Command {
+	flags := &NetshootUndeployFlagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "netshoot",
+		Short: "Remove netshoot pods",
+		Long:  "Remove the netshoot pods deployed for debugging",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if flags.Debug {
+				log.SetLevel(log.DebugLevel)
+			}
+
+			netshootDeploymentFilePath := "debug/netshoot-daemonset.yaml"
+			if flags.HostNetwork {
+				netshootDeploymentFilePath = "debug/netshoot-daemonset-host.yaml"
+			}
+
+			netshootDeploymentFile, err := box.Resolve(netshootDeploymentFilePath)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			opts := deploy.DeleteOptions{Timeout: flags.Timeout, RemoveFinalizers: flags.RemoveFinalizers}
+
+			var wg sync.WaitGroup
+			wg.Add(len(targetClusters))
+			for _, clName := range targetClusters {
+				go func(clName string) {
+					dynamicClient, mapper, err := cluster.GetDynamicClient(clName)
+					if err != nil {
+						log.Fatalf("%s %s", clName, err)
+					}
+
+					err = deploy.DeleteResources(clName, dynamicClient, mapper, netshootDeploymentFile.String(), "Netshoot", opts)
+					if err != nil {
+						log.Fatalf("%s %s", clName, err)
+					}
+					log.Infof("✔ Netshoot was removed from %s.", clName)
+					wg.Done()
+				}(clName)
+			}
+			wg.Wait()
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&flags.HostNetwork, "host-network", false, "remove the host network mode pods.")
+	cmd.Flags().DurationVar(&flags.Timeout, "timeout", defaults.WaitDurationResources, "how long to wait for each object to be deleted")
+	cmd.Flags().BoolVar(&flags.RemoveFinalizers, "remove-finalizers", false, "remove the finalizers of objects still terminating after the timeout")
+	cmd.Flags().BoolVarP(&flags.Debug, "debug", "v", false, "set log level to debug")
+	cmd.Flags().StringSliceVarP(&flags.Clusters, "clusters", "c", []string{}, "comma separated list of cluster names to remove from. eg: cl1,cl6,cl3")
+	return cmd
+}
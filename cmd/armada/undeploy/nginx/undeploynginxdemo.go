@@ -0,0 +1,75 @@
+package nginx
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/dimaunx/armada/pkg/defaults"
+	"github.com/dimaunx/armada/pkg/deploy"
+	"github.com/gobuffalo/packr/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// NginxUndeployFlagpole is a list of cli flags for undeploy nginx-demo command
+type NginxUndeployFlagpole struct {
+	Timeout          time.Duration
+	RemoveFinalizers bool
+	Debug            bool
+	Clusters         []string
+}
+
+// UndeployNginxDemoCommand returns a new cobra.Command under undeploy command for armada
+func UndeployNginxDemoCommand(box *packr.Box) *cobra.Command {
+	flags := &NginxUndeployFlagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "nginx-demo",
+		Short: "Remove nginx demo application service and pods",
+		Long:  "Remove nginx demo application service and pods",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if flags.Debug {
+				log.SetLevel(log.DebugLevel)
+			}
+
+			nginxDeploymentFile, err := box.Resolve("debug/nginx-demo-daemonset.yaml")
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			opts := deploy.DeleteOptions{Timeout: flags.Timeout, RemoveFinalizers: flags.RemoveFinalizers}
+
+			var wg sync.WaitGroup
+			wg.Add(len(targetClusters))
+			for _, clName := range targetClusters {
+				go func(clName string) {
+					dynamicClient, mapper, err := cluster.GetDynamicClient(clName)
+					if err != nil {
+						log.Fatalf("%s %s", clName, err)
+					}
+
+					err = deploy.DeleteResources(clName, dynamicClient, mapper, nginxDeploymentFile.String(), "Nginx", opts)
+					if err != nil {
+						log.Fatalf("%s %s", clName, err)
+					}
+					log.Infof("✔ Nginx demo was removed from %s.", clName)
+					wg.Done()
+				}(clName)
+			}
+			wg.Wait()
+			return nil
+		},
+	}
+	cmd.Flags().DurationVar(&flags.Timeout, "timeout", defaults.WaitDurationResources, "how long to wait for each object to be deleted")
+	cmd.Flags().BoolVar(&flags.RemoveFinalizers, "remove-finalizers", false, "remove the finalizers of objects still terminating after the timeout")
+	cmd.Flags().BoolVarP(&flags.Debug, "debug", "v", false, "set log level to debug")
+	cmd.Flags().StringSliceVarP(&flags.Clusters, "clusters", "c", []string{}, "comma separated list of cluster names to remove from. eg: cl1,cl6,cl3")
+	return cmd
+}
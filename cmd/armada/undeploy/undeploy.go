@@ -0,0 +1,107 @@
+package undeploy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dimaunx/armada/cmd/armada/undeploy/chart"
+	"github.com/dimaunx/armada/cmd/armada/undeploy/netshoot"
+	"github.com/dimaunx/armada/cmd/armada/undeploy/nginx"
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/dimaunx/armada/pkg/defaults"
+	"github.com/dimaunx/armada/pkg/deploy"
+	"github.com/gobuffalo/packr/v2"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// UndeployFlagpole is a list of cli flags for undeploy command
+type UndeployFlagpole struct {
+	// Files is a list of manifest files or directories to remove
+	Files []string
+
+	// Clusters is a list of cluster names to remove the resources from, all clusters if empty
+	Clusters []string
+
+	// Timeout is how long to wait for each object to be deleted
+	Timeout time.Duration
+
+	// RemoveFinalizers if to remove the finalizers of objects still terminating after the timeout
+	RemoveFinalizers bool
+
+	// Debug sets log level to debug
+	Debug bool
+}
+
+// UndeployCmd returns a new cobra.Command under root command for armada
+func UndeployCmd(box *packr.Box) *cobra.Command {
+	flags := &UndeployFlagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "undeploy",
+		Short: "Remove deployed resources",
+		Long: "Remove the resources of the manifests from --filename, or the resources of a deploy command, from the clusters. " +
+			"The manifests are rendered as go templates for each cluster like the manifests of deploy manifests command",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(flags.Files) == 0 {
+				return cmd.Help()
+			}
+
+			if flags.Debug {
+				log.SetLevel(log.DebugLevel)
+			}
+
+			manifests, err := deploy.ReadManifestFiles(flags.Files...)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			configs, err := cluster.GetClusterConfigs(targetClusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			opts := deploy.DeleteOptions{Timeout: flags.Timeout, RemoveFinalizers: flags.RemoveFinalizers}
+
+			var wg sync.WaitGroup
+			wg.Add(len(targetClusters))
+			for _, clName := range targetClusters {
+				go func(clName string) {
+					// rendered per cluster, the same way as by deploy manifests command
+					objects, err := cluster.RenderManifestObjects(configs[clName], cluster.SortConfigs(configs), manifests)
+					if err != nil {
+						log.Fatalf("%s %s", clName, err)
+					}
+
+					dynamicClient, mapper, err := cluster.GetDynamicClient(clName)
+					if err != nil {
+						log.Fatalf("%s %s", clName, err)
+					}
+
+					err = deploy.DeleteObjects(clName, dynamicClient, mapper, objects, opts)
+					if err != nil {
+						log.Fatalf("%s %s", clName, err)
+					}
+					log.Infof("✔ Manifests resources were removed from %s.", clName)
+					wg.Done()
+				}(clName)
+			}
+			wg.Wait()
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&flags.Files, "filename", "f", []string{}, "manifest files or directories to remove")
+	cmd.Flags().StringSliceVarP(&flags.Clusters, "clusters", "c", []string{}, "comma separated list of cluster names to remove the resources from. eg: cl1,cl6,cl3")
+	cmd.Flags().DurationVar(&flags.Timeout, "timeout", defaults.WaitDurationResources, "how long to wait for each object to be deleted")
+	cmd.Flags().BoolVar(&flags.RemoveFinalizers, "remove-finalizers", false, "remove the finalizers of objects still terminating after the timeout")
+	cmd.Flags().BoolVarP(&flags.Debug, "debug", "v", false, "set log level to debug")
+	cmd.AddCommand(chart.UndeployChartCommand())
+	cmd.AddCommand(netshoot.UndeployNetshootCommand(box))
+	cmd.AddCommand(nginx.UndeployNginxDemoCommand(box))
+	return cmd
+}
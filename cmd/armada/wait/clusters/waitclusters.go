@@ -0,0 +1,157 @@
+package clusters
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/dimaunx/armada/pkg/defaults"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	kind "sigs.k8s.io/kind/pkg/cluster"
+)
+
+// conditionReady is the only condition clusters can be waited for
+const conditionReady = "ready"
+
+// WaitClustersFlagpole is a list of cli flags for wait clusters command
+type WaitClustersFlagpole struct {
+	// Clusters is a list of cluster names to wait for, all clusters if empty
+	Clusters []string
+
+	// For is the condition to wait for
+	For string
+
+	// Timeout is how long to wait for each cluster
+	Timeout time.Duration
+
+	// Addons is a list of addons that must be ready too
+	Addons []string
+
+	// Debug sets log level to debug
+	Debug bool
+}
+
+// WaitClustersCommand returns a new cobra.Command under wait command for armada
+func WaitClustersCommand() *cobra.Command {
+	flags := &WaitClustersFlagpole{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "clusters",
+		Short: "Wait for clusters to be ready",
+		Long: "Wait until all the nodes of the clusters are ready, the CNI daemon set rolled out, coredns is available " +
+			"and the requested addons are ready. Exits with an error and a report of the clusters that are not ready on timeout.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			if flags.Debug {
+				log.SetLevel(log.DebugLevel)
+			}
+
+			if flags.For != conditionReady {
+				log.Fatalf("unsupported condition %q, supported: %s", flags.For, conditionReady)
+			}
+
+			targetClusters, err := cluster.GetTargetClusterNames(flags.Clusters)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			var mutex sync.Mutex
+			failures := map[string]error{}
+			var wg sync.WaitGroup
+			wg.Add(len(targetClusters))
+			for _, clName := range targetClusters {
+				go func(clName string) {
+					defer wg.Done()
+					err := ForClusterReady(clName, flags.Addons, flags.Timeout)
+					if err != nil {
+						mutex.Lock()
+						failures[clName] = err
+						mutex.Unlock()
+						return
+					}
+					log.Infof("✔ Cluster %q is ready.", clName)
+				}(clName)
+			}
+			wg.Wait()
+
+			if len(failures) > 0 {
+				report(targetClusters, failures)
+				log.Fatalf("%d of %d clusters are not ready", len(failures), len(targetClusters))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&flags.Clusters, "clusters", "c", []string{}, "comma separated list of cluster names to wait for. eg: cl1,cl6,cl3")
+	cmd.Flags().StringVar(&flags.For, "for", conditionReady, "condition to wait for, only ready is supported")
+	cmd.Flags().DurationVar(&flags.Timeout, "timeout", defaults.WaitDurationResources, "how long to wait for each cluster")
+	cmd.Flags().StringSliceVar(&flags.Addons, "addons", []string{}, fmt.Sprintf("comma separated list of addons that must be ready, %s or <resource>/<namespace>/<name>. eg: nginx-demo,deployments/kube-system/metrics-server", strings.Join(cluster.AddonNames(), ", ")))
+	cmd.Flags().BoolVarP(&flags.Debug, "debug", "v", false, "set log level to debug")
+	return cmd
+}
+
+// ForClusterReady waits up to timeout for all the cluster nodes to register and for them, its CNI, coredns and the addons
+// to be ready. The API server errors of a cluster that is still starting are retried within the timeout.
+func ForClusterReady(clName string, addons []string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	clientSet, err := cluster.GetClientSet(clName)
+	if err != nil {
+		return err
+	}
+
+	nodeList, err := kind.NewProvider().ListInternalNodes(clName)
+	if err != nil {
+		return err
+	}
+
+	// the CNI daemon sets are listed once the API server serves the registered nodes
+	_, err = cluster.WaitForNodes(clName, clientSet, len(nodeList), timeout)
+	if err != nil {
+		return err
+	}
+
+	dynamicClient, mapper, err := cluster.GetDynamicClient(clName)
+	if err != nil {
+		return err
+	}
+
+	objects, err := cluster.CniObjects(clientSet)
+	if err != nil {
+		return err
+	}
+	if len(objects) == 0 {
+		log.Warnf("No known CNI daemon set was found in %s, only the nodes readiness shows the CNI state.", clName)
+	}
+
+	for _, addon := range addons {
+		obj, err := cluster.AddonObject(mapper, addon)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, obj)
+	}
+	return cluster.WaitForReady(clName, clientSet, dynamicClient, mapper, objects, len(nodeList), time.Until(deadline))
+}
+
+// report prints the state of the clusters and the reasons of the clusters that are not ready
+func report(targetClusters []string, failures map[string]error) {
+	sort.Strings(targetClusters)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tREADY")
+	for _, clName := range targetClusters {
+		_, failed := failures[clName]
+		fmt.Fprintf(w, "%s\t%v\n", clName, !failed)
+	}
+	_ = w.Flush()
+
+	for _, clName := range targetClusters {
+		if err, failed := failures[clName]; failed {
+			fmt.Printf("\n%s is not ready: %v\n", clName, err)
+		}
+	}
+}
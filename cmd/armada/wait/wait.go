@@ -0,0 +1,18 @@
+package wait
+
+import (
+	"github.com/dimaunx/armada/cmd/armada/wait/clusters"
+	"github.com/spf13/cobra"
+)
+
+// WaitCmd returns a new cobra.Command under root command for armada
+func WaitCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Use:   "wait",
+		Short: "Wait for resources",
+		Long:  "Wait for resources to reach a condition",
+	}
+	cmd.AddCommand(clusters.WaitClustersCommand())
+	return cmd
+}
@@ -5,9 +5,10 @@ go 1.12
 require (
 	github.com/Masterminds/semver v1.5.0
 	github.com/Microsoft/go-winio v0.4.14 // indirect
-	github.com/docker/distribution v2.7.1+incompatible // indirect
+	github.com/docker/distribution v2.7.1+incompatible
 	github.com/docker/docker v1.13.1
 	github.com/docker/go-connections v0.4.0 // indirect
+	github.com/evanphx/json-patch v4.5.0+incompatible
 	github.com/gobuffalo/packr/v2 v2.7.1
 	github.com/imdario/mergo v0.3.8 // indirect
 	github.com/onsi/ginkgo v1.10.3
@@ -22,6 +23,7 @@ require (
 	k8s.io/apimachinery v0.0.0-20191123233150-4c4803ed55e3
 	k8s.io/client-go v11.0.0+incompatible
 	sigs.k8s.io/kind v0.6.1
+	sigs.k8s.io/yaml v1.1.0
 )
 
 // pinned 1.15.0
@@ -10,7 +10,6 @@ import (
 	"sync"
 
 	"github.com/dimaunx/armada/pkg/deploy"
-	"github.com/dimaunx/armada/pkg/wait"
 	"github.com/docker/docker/api/types/filters"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
@@ -21,20 +20,20 @@ import (
 	"github.com/gobuffalo/packr/v2"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
-	apiextclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
 	kind "sigs.k8s.io/kind/pkg/cluster"
 	kinderrors "sigs.k8s.io/kind/pkg/errors"
 )
 
 // Create creates cluster with kind
 func Create(cl *Config, provider *kind.Provider, box *packr.Box, wg *sync.WaitGroup) error {
-	currentDir, err := os.Getwd()
-	if err != nil {
-		return err
-	}
-
-	configDir := filepath.Join(currentDir, defaults.KindConfigDir)
-	err = os.MkdirAll(configDir, os.ModePerm)
+	configDir := defaults.HomePath(defaults.KindConfigDir)
+	err := os.MkdirAll(configDir, os.ModePerm)
 	if err != nil {
 		return err
 	}
@@ -85,15 +84,36 @@ func Destroy(clName string, provider *kind.Provider) error {
 		return err
 	}
 
-	_ = os.Remove(filepath.Join(defaults.KindConfigDir, "kind-config-"+clName+".yaml"))
-	_ = os.Remove(filepath.Join(defaults.LocalKubeConfigDir, "kind-config-"+clName))
-	_ = os.Remove(filepath.Join(defaults.ContainerKubeConfigDir, "kind-config-"+clName))
+	_ = os.Remove(defaults.HomePath(defaults.KindConfigDir, "kind-config-"+clName+".yaml"))
+	_ = os.Remove(defaults.HomePath(defaults.LocalKubeConfigDir, "kind-config-"+clName))
+	_ = os.Remove(defaults.HomePath(defaults.ContainerKubeConfigDir, "kind-config-"+clName))
 	_ = os.RemoveAll(filepath.Join(usr.HomeDir, ".kube", strings.Join([]string{"kind-config", clName}, "-")))
-	_ = os.RemoveAll(filepath.Join(defaults.KindLogsDir, clName))
+	_ = os.RemoveAll(defaults.HomePath(defaults.KindLogsDir, clName))
 
 	return nil
 }
 
+// GetTargetClusterNames returns the selected cluster names or the names of all the clusters created by armada if none are selected
+func GetTargetClusterNames(clusters []string) ([]string, error) {
+	var targetClusters []string
+	if len(clusters) > 0 {
+		return append(targetClusters, clusters...), nil
+	}
+
+	configFiles, err := ioutil.ReadDir(defaults.HomePath(defaults.KindConfigDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return targetClusters, nil
+		}
+		return nil, err
+	}
+	for _, configFile := range configFiles {
+		clName := strings.FieldsFunc(configFile.Name(), func(r rune) bool { return strings.ContainsRune(" -.", r) })[2]
+		targetClusters = append(targetClusters, clName)
+	}
+	return targetClusters, nil
+}
+
 // GetMasterDockerIP gets control plain master docker internal ip
 func GetMasterDockerIP(clName string) (string, error) {
 	ctx := context.Background()
@@ -157,23 +177,29 @@ func GetClientSet(clName string) (kubernetes.Interface, error) {
 	return clientSet, nil
 }
 
-// GetCrdClientSet returns apiextclientset interface
-func GetCrdClientSet(clName string) (apiextclientset.Interface, error) {
+// GetDynamicClient returns the dynamic client and a discovery backed rest mapper of the cluster
+func GetDynamicClient(clName string) (dynamic.Interface, meta.RESTMapper, error) {
 	kubeConfigFilePath, err := GetKubeConfigPath(clName)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	kconfig, err := clientcmd.BuildConfigFromFlags("", kubeConfigFilePath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	apiExtClientSet, err := apiextclientset.NewForConfig(kconfig)
+	dynamicClient, err := dynamic.NewForConfig(kconfig)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return apiExtClientSet, nil
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(kconfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+	return dynamicClient, mapper, nil
 }
 
 // FinalizeSetup creates custom environment
@@ -193,96 +219,44 @@ func FinalizeSetup(cl *Config, box *packr.Box, wg *sync.WaitGroup) error {
 		return err
 	}
 
-	apiExtClientSet, err := GetCrdClientSet(cl.Name)
+	dynamicClient, mapper, err := GetDynamicClient(cl.Name)
 	if err != nil {
 		return err
 	}
 
-	switch cl.Cni {
-	case "calico":
-		calicoDeploymentFile, err := GenerateCalicoDeploymentFile(cl, box)
-		if err != nil {
-			return err
-		}
-
-		calicoCrdFile, err := box.Resolve("tpl/calico-crd.yaml")
-		if err != nil {
-			return err
-		}
-
-		err = deploy.CrdResources(cl.Name, apiExtClientSet, calicoCrdFile.String())
-		if err != nil {
-			return err
-		}
-
-		err = deploy.Resources(cl.Name, clientSet, calicoDeploymentFile, "Calico")
-		if err != nil {
-			return err
-		}
-
-		err = wait.ForDaemonSetReady(cl.Name, clientSet, "kube-system", "calico-node")
-		if err != nil {
-			return err
-		}
-
-		err = wait.ForDeploymentReady(cl.Name, clientSet, "kube-system", "coredns")
-		if err != nil {
-			return err
-		}
-	case "flannel":
-		flannelDeploymentFile, err := GenerateFlannelDeploymentFile(cl, box)
-		if err != nil {
-			return err
-		}
-
-		err = deploy.Resources(cl.Name, clientSet, flannelDeploymentFile, "Flannel")
-		if err != nil {
-			return err
-		}
-
-		err = wait.ForDaemonSetReady(cl.Name, clientSet, "kube-system", "kube-flannel-ds-amd64")
-		if err != nil {
-			return err
-		}
-
-		err = wait.ForDeploymentReady(cl.Name, clientSet, "kube-system", "coredns")
-		if err != nil {
-			return err
-		}
-	case "weave":
-		weaveDeploymentFile, err := GenerateWeaveDeploymentFile(cl, box)
-		if err != nil {
-			return err
-		}
-
-		err = deploy.Resources(cl.Name, clientSet, weaveDeploymentFile, "Weave")
-		if err != nil {
-			return err
-		}
+	manifests, err := RenderManifests(cl, box)
+	if err != nil {
+		return err
+	}
 
-		err = wait.ForDaemonSetReady(cl.Name, clientSet, "kube-system", "weave-net")
+	// objects of all the manifests are applied together so CRDs are established before the resources that need them
+	var objects []*unstructured.Unstructured
+	for _, manifest := range manifests {
+		manifestObjects, err := deploy.Decode(manifest.Content)
 		if err != nil {
-			return err
+			return errors.Wrapf(err, "%s resources", manifest.Name)
 		}
+		objects = append(objects, manifestObjects...)
+	}
 
-		err = wait.ForDeploymentReady(cl.Name, clientSet, "kube-system", "coredns")
-		if err != nil {
-			return err
-		}
+	err = deploy.ApplyObjects(cl.Name, dynamicClient, mapper, objects)
+	if err != nil {
+		return err
 	}
 
-	if cl.Tiller {
-		tillerDeploymentFile, err := box.Resolve("helm/tiller-deployment.yaml")
-		if err != nil {
-			return err
-		}
+	provider := kind.NewProvider()
+	nodeList, err := provider.ListInternalNodes(cl.Name)
+	if err != nil {
+		return err
+	}
 
-		err = deploy.Resources(cl.Name, clientSet, tillerDeploymentFile.String(), "Tiller")
-		if err != nil {
-			return err
-		}
+	err = WaitForReady(cl.Name, clientSet, dynamicClient, mapper, objects, len(nodeList), defaults.WaitDurationResources)
+	if err != nil {
+		return err
+	}
 
-		err = wait.ForDeploymentReady(cl.Name, clientSet, "kube-system", "tiller-deploy")
+	if cl.KubeProxyMode == KubeProxyModeDisabled {
+		err = DisableKubeProxy(cl.Name, clientSet, provider)
 		if err != nil {
 			return err
 		}
@@ -118,6 +118,36 @@ var _ = Describe("cluster tests", func() {
 
 			_ = os.RemoveAll(configPath)
 		})
+		It("Should generate correct kind config for cluster with k8s version lower then 1.12", func() {
+
+			flags := &createclustercmd.CreateClusterFlagpole{
+				ImageName: "test/test:v1.11.10",
+				Kindnet:   true,
+			}
+
+			currentDir, err := os.Getwd()
+			Ω(err).ShouldNot(HaveOccurred())
+
+			cni := createclustercmd.GetCniFromFlags(flags)
+			cl, err := cluster.PopulateConfig(1, flags.ImageName, cni, true, true, false, 0)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			configDir := filepath.Join(currentDir, "testdata/kind")
+			gf := filepath.Join(configDir, "v1alpha2.golden")
+			cl.Name = "cl6"
+			cl.DNSDomain = "cl6.local"
+			configPath, err := cluster.GenerateKindConfig(cl, configDir, box)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			golden, err := ioutil.ReadFile(gf)
+			Ω(err).ShouldNot(HaveOccurred())
+			actual, err := ioutil.ReadFile(configPath)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Expect(string(actual)).Should(Equal(string(golden)))
+
+			_ = os.RemoveAll(configPath)
+		})
 		It("Should generate correct kind config for cluster with k8s version lower then 1.15", func() {
 
 			flags := &createclustercmd.CreateClusterFlagpole{
@@ -9,12 +9,12 @@ import (
 
 // GenerateCalicoDeploymentFile generates calico deployment file from template
 func GenerateCalicoDeploymentFile(cl *Config, box *packr.Box) (string, error) {
-	calicoDeploymentTemplate, err := box.Resolve("tpl/calico-daemonset.yaml")
+	calicoDeploymentTemplate, err := resolveCniTemplate(box, "calico", cl.CniVersion, "calico-daemonset.yaml")
 	if err != nil {
 		return "", err
 	}
 
-	t, err := template.New("calico").Parse(calicoDeploymentTemplate.String())
+	t, err := template.New("calico").Parse(calicoDeploymentTemplate)
 	if err != nil {
 		return "", err
 	}
@@ -27,14 +27,19 @@ func GenerateCalicoDeploymentFile(cl *Config, box *packr.Box) (string, error) {
 	return calicoDeploymentFile.String(), nil
 }
 
+// GenerateCalicoCrdFile returns calico custom resource definitions file
+func GenerateCalicoCrdFile(cl *Config, box *packr.Box) (string, error) {
+	return resolveCniTemplate(box, "calico", cl.CniVersion, "calico-crd.yaml")
+}
+
 // GenerateFlannelDeploymentFile generates flannel deployment file from template
 func GenerateFlannelDeploymentFile(cl *Config, box *packr.Box) (string, error) {
-	flannelDeploymentTemplate, err := box.Resolve("tpl/flannel-daemonset.yaml")
+	flannelDeploymentTemplate, err := resolveCniTemplate(box, "flannel", cl.CniVersion, "flannel-daemonset.yaml")
 	if err != nil {
 		return "", err
 	}
 
-	t, err := template.New("flannel").Parse(flannelDeploymentTemplate.String())
+	t, err := template.New("flannel").Parse(flannelDeploymentTemplate)
 	if err != nil {
 		return "", err
 	}
@@ -49,12 +54,12 @@ func GenerateFlannelDeploymentFile(cl *Config, box *packr.Box) (string, error) {
 
 // GenerateWeaveDeploymentFile generates weave deployment file from template
 func GenerateWeaveDeploymentFile(cl *Config, box *packr.Box) (string, error) {
-	weaveDeploymentTemplate, err := box.Resolve("tpl/weave-daemonset.yaml")
+	weaveDeploymentTemplate, err := resolveCniTemplate(box, "weave", cl.CniVersion, "weave-daemonset.yaml")
 	if err != nil {
 		return "", err
 	}
 
-	t, err := template.New("weave").Parse(weaveDeploymentTemplate.String())
+	t, err := template.New("weave").Parse(weaveDeploymentTemplate)
 	if err != nil {
 		return "", err
 	}
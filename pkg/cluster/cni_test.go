@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/dimaunx/armada/pkg/cluster"
 	"github.com/gobuffalo/packr/v2"
@@ -66,5 +67,92 @@ var _ = Describe("cni tests", func() {
 
 			Expect(actual).Should(Equal(string(golden)))
 		})
+		It("Should generate correct calico deployment file with vxlan encapsulation", func() {
+			currentDir, err := os.Getwd()
+			Ω(err).ShouldNot(HaveOccurred())
+
+			cl := &cluster.Config{
+				PodSubnet: "1.2.3.4/16",
+				Calico:    cluster.CalicoOptions{Encapsulation: "vxlan"},
+			}
+			Ω(cluster.ValidateCniOptions(cl)).Should(Succeed())
+
+			configDir := filepath.Join(currentDir, "testdata/cni")
+			actual, err := cluster.GenerateCalicoDeploymentFile(cl, box)
+			Ω(err).ShouldNot(HaveOccurred())
+			golden, err := ioutil.ReadFile(filepath.Join(configDir, "calico_vxlan_deployment.golden"))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Expect(actual).Should(Equal(string(golden)))
+		})
+		It("Should generate correct flannel deployment file with host-gw backend and mtu", func() {
+			currentDir, err := os.Getwd()
+			Ω(err).ShouldNot(HaveOccurred())
+
+			cl := &cluster.Config{
+				PodSubnet: "1.2.3.4/16",
+				CniMTU:    1400,
+				Flannel:   cluster.FlannelOptions{Backend: "host-gw"},
+			}
+			Ω(cluster.ValidateCniOptions(cl)).Should(Succeed())
+
+			configDir := filepath.Join(currentDir, "testdata/cni")
+			actual, err := cluster.GenerateFlannelDeploymentFile(cl, box)
+			Ω(err).ShouldNot(HaveOccurred())
+			golden, err := ioutil.ReadFile(filepath.Join(configDir, "flannel_hostgw_deployment.golden"))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Expect(actual).Should(Equal(string(golden)))
+		})
+		It("Should generate correct weave deployment file with encryption and without fast datapath", func() {
+			currentDir, err := os.Getwd()
+			Ω(err).ShouldNot(HaveOccurred())
+
+			cl := &cluster.Config{
+				Name:      "cl1",
+				PodSubnet: "1.2.3.4/14",
+				CniMTU:    1376,
+				Weave:     cluster.WeaveOptions{Password: "secret", DisableFastDatapath: true},
+			}
+
+			configDir := filepath.Join(currentDir, "testdata/cni")
+			actual, err := cluster.GenerateWeaveDeploymentFile(cl, box)
+			Ω(err).ShouldNot(HaveOccurred())
+			golden, err := ioutil.ReadFile(filepath.Join(configDir, "weave_encrypted_deployment.golden"))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Expect(actual).Should(Equal(string(golden)))
+		})
+	})
+
+	Context("Cni options", func() {
+		It("Should set calico ipip mode, backend and mtu from the encapsulation", func() {
+			for encapsulation, expected := range map[string][]string{
+				"":      {"Always", "bird", "1440"},
+				"IPIP":  {"Always", "bird", "1440"},
+				"VXLAN": {"Never", "vxlan", "1410"},
+				"none":  {"Never", "bird", "1500"},
+			} {
+				cl := &cluster.Config{Calico: cluster.CalicoOptions{Encapsulation: encapsulation}}
+				Ω(cluster.ValidateCniOptions(cl)).Should(Succeed())
+				Expect([]string{cl.CalicoIPIPMode(), cl.CalicoBackend(), strconv.Itoa(cl.CalicoVethMTU())}).Should(Equal(expected))
+			}
+
+			cl := &cluster.Config{CniMTU: 1300, Calico: cluster.CalicoOptions{CrossSubnet: true}}
+			Ω(cluster.ValidateCniOptions(cl)).Should(Succeed())
+			Expect(cl.CalicoIPIPMode()).Should(Equal("CrossSubnet"))
+			Expect(cl.CalicoVethMTU()).Should(Equal(1300))
+		})
+		It("Should return error for invalid cni options", func() {
+			for _, cl := range []*cluster.Config{
+				{Calico: cluster.CalicoOptions{Encapsulation: "gre"}},
+				{Calico: cluster.CalicoOptions{Encapsulation: "VXLAN", CrossSubnet: true}},
+				{Calico: cluster.CalicoOptions{Encapsulation: "None", CrossSubnet: true}},
+				{Flannel: cluster.FlannelOptions{Backend: "udp"}},
+				{CniMTU: -1},
+			} {
+				Ω(cluster.ValidateCniOptions(cl)).ShouldNot(Succeed())
+			}
+		})
 	})
 })
@@ -0,0 +1,119 @@
+package cluster
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Calico ip pool encapsulation modes
+const (
+	CalicoEncapsulationIPIP  = "IPIP"
+	CalicoEncapsulationVXLAN = "VXLAN"
+	CalicoEncapsulationNone  = "None"
+)
+
+// Flannel backends
+const (
+	FlannelBackendVxlan  = "vxlan"
+	FlannelBackendHostGw = "host-gw"
+)
+
+// CalicoOptions are the calico specific options
+type CalicoOptions struct {
+	// Encapsulation is the ip pool encapsulation: IPIP, VXLAN or None for BGP only, IPIP if empty
+	Encapsulation string
+
+	// CrossSubnet if to encapsulate only the traffic crossing subnet boundaries
+	CrossSubnet bool
+}
+
+// FlannelOptions are the flannel specific options
+type FlannelOptions struct {
+	// Backend is the flannel backend: vxlan or host-gw, vxlan if empty
+	Backend string
+}
+
+// WeaveOptions are the weave specific options
+type WeaveOptions struct {
+	// Password enables weave encryption with the password
+	Password string
+
+	// DisableFastDatapath if to use the sleeve overlay only
+	DisableFastDatapath bool
+}
+
+// ValidateCniOptions normalizes and validates the cni options of the cluster
+func ValidateCniOptions(cl *Config) error {
+	if cl.CniMTU < 0 {
+		return errors.Errorf("%q: invalid mtu %d", cl.Name, cl.CniMTU)
+	}
+
+	switch strings.ToLower(cl.Calico.Encapsulation) {
+	case "", "ipip":
+		cl.Calico.Encapsulation = ""
+	case "vxlan":
+		cl.Calico.Encapsulation = CalicoEncapsulationVXLAN
+		if cl.Calico.CrossSubnet {
+			return errors.Errorf("%q: calico cross subnet mode is supported only with IPIP encapsulation", cl.Name)
+		}
+	case "none":
+		cl.Calico.Encapsulation = CalicoEncapsulationNone
+		if cl.Calico.CrossSubnet {
+			return errors.Errorf("%q: calico cross subnet mode requires encapsulation", cl.Name)
+		}
+	default:
+		return errors.Errorf("%q: unknown calico encapsulation %q, supported: IPIP, VXLAN, None", cl.Name, cl.Calico.Encapsulation)
+	}
+
+	switch strings.ToLower(cl.Flannel.Backend) {
+	case "", FlannelBackendVxlan:
+		cl.Flannel.Backend = ""
+	case FlannelBackendHostGw:
+		cl.Flannel.Backend = FlannelBackendHostGw
+	default:
+		return errors.Errorf("%q: unknown flannel backend %q, supported: vxlan, host-gw", cl.Name, cl.Flannel.Backend)
+	}
+	return nil
+}
+
+// CalicoBackend returns the calico networking backend for the encapsulation
+func (cl *Config) CalicoBackend() string {
+	if cl.Calico.Encapsulation == CalicoEncapsulationVXLAN {
+		return "vxlan"
+	}
+	return "bird"
+}
+
+// CalicoIPIPMode returns the ip pool IPIP mode
+func (cl *Config) CalicoIPIPMode() string {
+	switch {
+	case cl.Calico.Encapsulation == CalicoEncapsulationVXLAN || cl.Calico.Encapsulation == CalicoEncapsulationNone:
+		return "Never"
+	case cl.Calico.CrossSubnet:
+		return "CrossSubnet"
+	}
+	return "Always"
+}
+
+// CalicoVethMTU returns the configured mtu or the one recommended by calico for the encapsulation
+func (cl *Config) CalicoVethMTU() int {
+	if cl.CniMTU > 0 {
+		return cl.CniMTU
+	}
+	switch cl.Calico.Encapsulation {
+	case CalicoEncapsulationVXLAN:
+		return 1410
+	case CalicoEncapsulationNone:
+		return 1500
+	}
+	return 1440
+}
+
+// FlannelBackend returns the flannel backend type
+func (cl *Config) FlannelBackend() string {
+	if cl.Flannel.Backend == "" {
+		return FlannelBackendVxlan
+	}
+	return cl.Flannel.Backend
+}
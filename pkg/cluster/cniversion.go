@@ -0,0 +1,174 @@
+package cluster
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/gobuffalo/packr/v2"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// cniTemplatesDir is the box directory with the versioned cni templates, eg: tpl/cni/calico/3.9.3/calico-daemonset.yaml
+const cniTemplatesDir = "tpl/cni"
+
+// cniMetadataFile describes a cni version, only the directories with this file are listed as versions
+const cniMetadataFile = "cni.yaml"
+
+// CniVersion is a version of cni manifests from the configs box
+type CniVersion struct {
+	// Cni is the cni name
+	Cni string `yaml:"-"`
+
+	// Version is the cni release version
+	Version string `yaml:"-"`
+
+	// Kubernetes is the kubernetes versions constraint supported by the manifests, eg: >= 1.14
+	Kubernetes string `yaml:"kubernetes"`
+
+	// Default if the version is used when none is selected
+	Default bool `yaml:"default"`
+}
+
+// ParseCni splits a cni selection into the cni name and version, eg: calico@3.10
+func ParseCni(value string) (string, string) {
+	parts := strings.SplitN(value, "@", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], strings.TrimPrefix(parts[1], "v")
+}
+
+// ListCniVersions returns the versions of the cni found in the box, newest first
+func ListCniVersions(box *packr.Box, cni string) ([]CniVersion, error) {
+	var versions []CniVersion
+	prefix := path.Join(cniTemplatesDir, cni) + "/"
+	for _, name := range box.List() {
+		name = path.Clean(strings.Replace(name, "\\", "/", -1))
+		if !strings.HasPrefix(name, prefix) || path.Base(name) != cniMetadataFile {
+			continue
+		}
+
+		version := path.Dir(strings.TrimPrefix(name, prefix))
+		if strings.Contains(version, "/") {
+			continue
+		}
+		if _, err := semver.NewVersion(version); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s version directory %s", cni, path.Dir(name))
+		}
+
+		metadata, err := box.FindString(name)
+		if err != nil {
+			return nil, err
+		}
+
+		cniVersion := CniVersion{}
+		if err := yaml.Unmarshal([]byte(metadata), &cniVersion); err != nil {
+			return nil, errors.Wrapf(err, "parsing %s", name)
+		}
+		cniVersion.Cni = cni
+		cniVersion.Version = version
+		versions = append(versions, cniVersion)
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return semver.MustParse(versions[i].Version).GreaterThan(semver.MustParse(versions[j].Version))
+	})
+	return versions, nil
+}
+
+// FindCniVersion returns the cni version from the box. A major.minor version selects the newest patch release,
+// an empty version selects the default one.
+func FindCniVersion(box *packr.Box, cni, version string) (*CniVersion, error) {
+	versions, err := ListCniVersions(box, cni)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, errors.Errorf("unknown cni %q, no manifests found in %s", cni, path.Join(cniTemplatesDir, cni))
+	}
+
+	if version == "" {
+		for i := range versions {
+			if versions[i].Default {
+				return &versions[i], nil
+			}
+		}
+		return &versions[0], nil
+	}
+
+	constraint, err := semver.NewConstraint("~" + version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s version %q", cni, version)
+	}
+
+	exact := strings.Count(version, ".") == 2
+	var available []string
+	for i := range versions {
+		ver := semver.MustParse(versions[i].Version)
+		if exact && versions[i].Version == version || !exact && constraint.Check(ver) {
+			return &versions[i], nil
+		}
+		available = append(available, versions[i].Version)
+	}
+	return nil, errors.Errorf("%s version %q not found, available versions: %s", cni, version, strings.Join(available, ", "))
+}
+
+// CheckKubernetesVersion returns an error if the cni version does not support the kubernetes version
+func (c *CniVersion) CheckKubernetesVersion(ver *semver.Version) error {
+	if c.Kubernetes == "" {
+		return nil
+	}
+
+	constraint, err := semver.NewConstraint(c.Kubernetes)
+	if err != nil {
+		return errors.Wrapf(err, "invalid kubernetes constraint of %s %s", c.Cni, c.Version)
+	}
+	if !constraint.Check(releaseVersion(ver)) {
+		return errors.Errorf("%s %s is not supported with kubernetes %s, supported versions: %s", c.Cni, c.Version, ver, c.Kubernetes)
+	}
+	return nil
+}
+
+// SetCniVersion resolves the selected cni version of the cluster and checks it supports the cluster kubernetes version
+func SetCniVersion(cl *Config, box *packr.Box) error {
+	if cl.Cni == "kindnet" || cl.Cni == "" {
+		if cl.CniVersion != "" {
+			return errors.Errorf("%q: kindnet version is defined by the node image", cl.Name)
+		}
+		return nil
+	}
+
+	cniVersion, err := FindCniVersion(box, cl.Cni, cl.CniVersion)
+	if err != nil {
+		return errors.Wrapf(err, "%q", cl.Name)
+	}
+
+	if cl.KubernetesVersion != "" {
+		ver, err := semver.NewVersion(cl.KubernetesVersion)
+		if err != nil {
+			return err
+		}
+		if err := cniVersion.CheckKubernetesVersion(ver); err != nil {
+			return errors.Wrapf(err, "%q", cl.Name)
+		}
+	}
+	cl.CniVersion = cniVersion.Version
+	return nil
+}
+
+// resolveCniTemplate returns the cni template of the version, the default version if empty
+func resolveCniTemplate(box *packr.Box, cni, version, name string) (string, error) {
+	cniVersion, err := FindCniVersion(box, cni, version)
+	if err != nil {
+		return "", err
+	}
+
+	cniTemplate, err := box.Resolve(path.Join(cniTemplatesDir, cni, cniVersion.Version, name))
+	if err != nil {
+		return "", err
+	}
+	return cniTemplate.String(), nil
+}
@@ -0,0 +1,85 @@
+package cluster_test
+
+import (
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/gobuffalo/packr/v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("cni version tests", func() {
+
+	box := packr.New("configs", "../../configs")
+
+	Context("Cni versions", func() {
+		It("Should split the cni name and version", func() {
+			cni, version := cluster.ParseCni("calico@v3.9")
+			Expect(cni).Should(Equal("calico"))
+			Expect(version).Should(Equal("3.9"))
+
+			cni, version = cluster.ParseCni("weave")
+			Expect(cni).Should(Equal("weave"))
+			Expect(version).Should(BeEmpty())
+		})
+		It("Should list the embedded versions newest first", func() {
+			versions, err := cluster.ListCniVersions(box, "weave")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			var names []string
+			for _, v := range versions {
+				names = append(names, v.Version)
+			}
+			Expect(names).Should(Equal([]string{"2.6.0", "2.5.2"}))
+		})
+		It("Should select the default, exact and newest patch versions", func() {
+			for requested, expected := range map[string]string{
+				"":      "2.6.0",
+				"2.5.2": "2.5.2",
+				"2.5":   "2.5.2",
+				"2.6.0": "2.6.0",
+			} {
+				if expected == "" {
+					_, err := cluster.FindCniVersion(box, "weave", requested)
+					Ω(err).Should(HaveOccurred())
+					continue
+				}
+				cniVersion, err := cluster.FindCniVersion(box, "weave", requested)
+				Ω(err).ShouldNot(HaveOccurred())
+				Expect(cniVersion.Version).Should(Equal(expected))
+			}
+		})
+		It("Should return error for unknown cni and version", func() {
+			_, err := cluster.FindCniVersion(box, "cilium", "")
+			Ω(err).Should(HaveOccurred())
+			_, err = cluster.FindCniVersion(box, "calico", "3.10")
+			Ω(err).Should(HaveOccurred())
+		})
+		It("Should set the cni version from the selection", func() {
+			cl, err := cluster.PopulateConfig(1, "kindest/node:v1.16.3", "weave@2.5", false, false, false, 0)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(cl.Cni).Should(Equal("weave"))
+
+			err = cluster.SetCniVersion(cl, box)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(cl.CniVersion).Should(Equal("2.5.2"))
+
+			deploymentFile, err := cluster.GenerateWeaveDeploymentFile(cl, box)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(deploymentFile).Should(ContainSubstring("docker.io/weaveworks/weave-kube:2.5.2"))
+		})
+		It("Should return error if cni does not support the kubernetes version", func() {
+			cl, err := cluster.PopulateConfig(1, "kindest/node:v1.13.12", "calico", false, false, false, 0)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			err = cluster.SetCniVersion(cl, box)
+			Ω(err).Should(HaveOccurred())
+		})
+		It("Should return error if kindnet version is selected", func() {
+			cl, err := cluster.PopulateConfig(1, "kindest/node:v1.16.3", "kindnet@0.5", false, false, false, 0)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			err = cluster.SetCniVersion(cl, box)
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+})
@@ -10,8 +10,6 @@ import (
 	"text/template"
 	"time"
 
-	"github.com/Masterminds/semver"
-
 	"github.com/dimaunx/armada/pkg/defaults"
 	"github.com/gobuffalo/packr/v2"
 	"github.com/pkg/errors"
@@ -23,6 +21,24 @@ type Config struct {
 	// Cni is a name of the cni that will be installed for a cluster
 	Cni string
 
+	// CniVersion is the version of the cni manifests, the default version if empty
+	CniVersion string
+
+	// CniMTU is the pod network mtu, the cni default if zero
+	CniMTU int
+
+	// Calico are the calico options
+	Calico CalicoOptions
+
+	// Flannel are the flannel options
+	Flannel FlannelOptions
+
+	// Weave are the weave options
+	Weave WeaveOptions
+
+	// KubeProxyMode is the kube-proxy mode: ipvs or disabled, iptables if empty
+	KubeProxyMode string
+
 	// Name is a cluster name
 	Name string
 
@@ -38,6 +54,9 @@ type Config struct {
 	// // KubeAdminAPIVersion for each cluster
 	KubeAdminAPIVersion string
 
+	// KubernetesVersion is the kubernetes version of the node image
+	KubernetesVersion string
+
 	// NumWorkers is the number of worker nodes
 	NumWorkers int
 
@@ -64,7 +83,7 @@ func GenerateKindConfig(cl *Config, configDir string, box *packr.Box) (string, e
 		return "", err
 	}
 
-	t, err := template.New("config").Funcs(template.FuncMap{"iterate": iterate}).Parse(kindConfigFileTemplate.String())
+	t, err := template.New("config").Funcs(template.FuncMap{"iterate": iterate, "kubeadmConfigKind": KubeAdminConfigKind}).Parse(kindConfigFileTemplate.String())
 	if err != nil {
 		return "", err
 	}
@@ -95,10 +114,12 @@ func PopulateConfig(i int, image, cni string, retain, tiller, overlap bool, wait
 		return nil, err
 	}
 
+	cni, cniVersion := ParseCni(cni)
 	cl := &Config{
 		Name:                defaults.ClusterNameBase + strconv.Itoa(i),
 		NodeImageName:       image,
 		Cni:                 cni,
+		CniVersion:          cniVersion,
 		NumWorkers:          defaults.NumWorkers,
 		DNSDomain:           defaults.ClusterNameBase + strconv.Itoa(i) + ".local",
 		KubeAdminAPIVersion: defaults.KubeAdminAPIVersion,
@@ -125,17 +146,15 @@ func PopulateConfig(i int, image, cni string, retain, tiller, overlap bool, wait
 		cl.WaitForReady = 0
 	}
 
-	if image != "" {
-		tgt := semver.MustParse("1.15")
-		results := strings.Split(image, ":v")
-		if len(results) == 2 {
-			sver := semver.MustParse(results[len(results)-1])
-			if sver.LessThan(tgt) {
-				cl.KubeAdminAPIVersion = "kubeadm.k8s.io/v1beta1"
-			}
-		} else {
-			return nil, errors.Errorf("%q: Could not extract version from %s, split is by ':v', example of correct image name: kindest/node:v1.15.3.", cl.Name, cl.NodeImageName)
-		}
+	ver, err := GetNodeImageVersion(image)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%q", cl.Name)
+	}
+	cl.KubernetesVersion = ver.String()
+
+	cl.KubeAdminAPIVersion, err = GetKubeAdminAPIVersion(ver)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%q", cl.Name)
 	}
 	return cl, nil
 }
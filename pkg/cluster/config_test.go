@@ -33,6 +33,7 @@ var _ = Describe("config tests", func() {
 				ServiceSubnet:       "100.1.0.0/16",
 				DNSDomain:           defaults.ClusterNameBase + strconv.Itoa(1) + ".local",
 				KubeAdminAPIVersion: "kubeadm.k8s.io/v1beta2",
+				KubernetesVersion:   "1.16.3",
 				NumWorkers:          defaults.NumWorkers,
 				KubeConfigFilePath:  filepath.Join(usr.HomeDir, ".kube", "kind-config-"+defaults.ClusterNameBase+strconv.Itoa(1)),
 				WaitForReady:        5 * time.Minute,
@@ -43,7 +44,7 @@ var _ = Describe("config tests", func() {
 		})
 	})
 	Context("Custom flags", func() {
-		It("Should set KubeAdminAPIVersion to kubeadm.k8s.io/v1beta1", func() {
+		It("Should set KubeAdminAPIVersion to kubeadm.k8s.io/v1alpha2", func() {
 			flags := &createclustercmd.CreateClusterFlagpole{
 				ImageName: "kindest/node:v1.11.1",
 				Weave:     true,
@@ -59,7 +60,8 @@ var _ = Describe("config tests", func() {
 				PodSubnet:           "10.4.0.0/14",
 				ServiceSubnet:       "100.1.0.0/16",
 				DNSDomain:           defaults.ClusterNameBase + strconv.Itoa(1) + ".local",
-				KubeAdminAPIVersion: "kubeadm.k8s.io/v1beta1",
+				KubeAdminAPIVersion: "kubeadm.k8s.io/v1alpha2",
+				KubernetesVersion:   "1.11.1",
 				NumWorkers:          defaults.NumWorkers,
 				KubeConfigFilePath:  filepath.Join(usr.HomeDir, ".kube", "kind-config-"+defaults.ClusterNameBase+strconv.Itoa(1)),
 				WaitForReady:        0,
@@ -85,6 +87,7 @@ var _ = Describe("config tests", func() {
 				ServiceSubnet:       "100.1.0.0/16",
 				DNSDomain:           defaults.ClusterNameBase + strconv.Itoa(1) + ".local",
 				KubeAdminAPIVersion: "kubeadm.k8s.io/v1beta2",
+				KubernetesVersion:   "1.16.3",
 				NumWorkers:          defaults.NumWorkers,
 				KubeConfigFilePath:  filepath.Join(usr.HomeDir, ".kube", "kind-config-"+defaults.ClusterNameBase+strconv.Itoa(1)),
 				WaitForReady:        5 * time.Minute,
@@ -110,6 +113,7 @@ var _ = Describe("config tests", func() {
 				ServiceSubnet:       "100.1.0.0/16",
 				DNSDomain:           defaults.ClusterNameBase + strconv.Itoa(1) + ".local",
 				KubeAdminAPIVersion: "kubeadm.k8s.io/v1beta2",
+				KubernetesVersion:   "1.16.3",
 				NumWorkers:          defaults.NumWorkers,
 				KubeConfigFilePath:  filepath.Join(usr.HomeDir, ".kube", "kind-config-"+defaults.ClusterNameBase+strconv.Itoa(1)),
 				WaitForReady:        5 * time.Minute,
@@ -120,7 +124,7 @@ var _ = Describe("config tests", func() {
 		})
 		It("Should return error with invalid node image name", func() {
 			flags := &createclustercmd.CreateClusterFlagpole{
-				ImageName: "kindest/node:1.16.3",
+				ImageName: "kindest/node:latest",
 			}
 			cni := createclustercmd.GetCniFromFlags(flags)
 			got, err := cluster.PopulateConfig(1, flags.ImageName, cni, true, true, true, 0)
@@ -128,6 +132,29 @@ var _ = Describe("config tests", func() {
 			Expect(got).To(BeNil())
 			Expect(err).NotTo(BeNil())
 		})
+		It("Should resolve kubernetes version of custom, digest pinned and private registry images", func() {
+			for image, expected := range map[string]string{
+				"kindest/node:1.16.3":                          "1.16.3",
+				"kindest/node:v1.16.3-rc.1-custom":             "1.16.3-rc.1-custom",
+				"registry.local:5000/kindest/node:v1.14.9":     "1.14.9",
+				"kindest/node:v1.15.6@sha256:0123456789abcdef": "1.15.6",
+			} {
+				cl, err := cluster.PopulateConfig(1, image, "calico", false, false, false, 0)
+				Ω(err).ShouldNot(HaveOccurred())
+				Expect(cl.KubernetesVersion).Should(Equal(expected))
+			}
+		})
+		It("Should set KubeAdminAPIVersion from the kubernetes version", func() {
+			for image, expected := range map[string]string{
+				"kindest/node:v1.12.10":      "kubeadm.k8s.io/v1alpha3",
+				"kindest/node:v1.13.12":      "kubeadm.k8s.io/v1beta1",
+				"kindest/node:v1.15.0-beta1": "kubeadm.k8s.io/v1beta2",
+			} {
+				cl, err := cluster.PopulateConfig(1, image, "kindnet", false, false, false, 0)
+				Ω(err).ShouldNot(HaveOccurred())
+				Expect(cl.KubeAdminAPIVersion).Should(Equal(expected))
+			}
+		})
 		It("Should set Cni to weave and WaitForReady should be zero", func() {
 			flags := &createclustercmd.CreateClusterFlagpole{
 				Weave: true,
@@ -144,6 +171,7 @@ var _ = Describe("config tests", func() {
 				ServiceSubnet:       "100.1.0.0/16",
 				DNSDomain:           defaults.ClusterNameBase + strconv.Itoa(1) + ".local",
 				KubeAdminAPIVersion: defaults.KubeAdminAPIVersion,
+				KubernetesVersion:   "1.16.3",
 				NumWorkers:          defaults.NumWorkers,
 				KubeConfigFilePath:  filepath.Join(usr.HomeDir, ".kube", "kind-config-"+defaults.ClusterNameBase+strconv.Itoa(1)),
 				WaitForReady:        0,
@@ -168,6 +196,7 @@ var _ = Describe("config tests", func() {
 				ServiceSubnet:       "100.1.0.0/16",
 				DNSDomain:           defaults.ClusterNameBase + strconv.Itoa(1) + ".local",
 				KubeAdminAPIVersion: defaults.KubeAdminAPIVersion,
+				KubernetesVersion:   "1.16.3",
 				NumWorkers:          defaults.NumWorkers,
 				KubeConfigFilePath:  filepath.Join(usr.HomeDir, ".kube", "kind-config-"+defaults.ClusterNameBase+strconv.Itoa(1)),
 				WaitForReady:        0,
@@ -192,6 +221,7 @@ var _ = Describe("config tests", func() {
 				ServiceSubnet:       "100.1.0.0/16",
 				DNSDomain:           defaults.ClusterNameBase + strconv.Itoa(1) + ".local",
 				KubeAdminAPIVersion: defaults.KubeAdminAPIVersion,
+				KubernetesVersion:   "1.16.3",
 				NumWorkers:          defaults.NumWorkers,
 				KubeConfigFilePath:  filepath.Join(usr.HomeDir, ".kube", "kind-config-"+defaults.ClusterNameBase+strconv.Itoa(1)),
 				WaitForReady:        0,
@@ -226,6 +256,7 @@ var _ = Describe("config tests", func() {
 					ServiceSubnet:       "100.0.0.0/16",
 					DNSDomain:           defaults.ClusterNameBase + strconv.Itoa(1) + ".local",
 					KubeAdminAPIVersion: defaults.KubeAdminAPIVersion,
+					KubernetesVersion:   "1.16.3",
 					NumWorkers:          defaults.NumWorkers,
 					KubeConfigFilePath:  filepath.Join(usr.HomeDir, ".kube", "kind-config-"+defaults.ClusterNameBase+strconv.Itoa(1)),
 					WaitForReady:        0,
@@ -240,6 +271,7 @@ var _ = Describe("config tests", func() {
 					ServiceSubnet:       "100.0.0.0/16",
 					DNSDomain:           defaults.ClusterNameBase + strconv.Itoa(2) + ".local",
 					KubeAdminAPIVersion: defaults.KubeAdminAPIVersion,
+					KubernetesVersion:   "1.16.3",
 					NumWorkers:          defaults.NumWorkers,
 					KubeConfigFilePath:  filepath.Join(usr.HomeDir, ".kube", "kind-config-"+defaults.ClusterNameBase+strconv.Itoa(2)),
 					WaitForReady:        0,
@@ -1,144 +1,313 @@
 package cluster
 
 import (
-	"context"
+	"fmt"
 	"io/ioutil"
-	"net"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/dimaunx/armada/pkg/defaults"
-	dockertypes "github.com/docker/docker/api/types"
-	"github.com/docker/docker/api/types/filters"
-	dockerclient "github.com/docker/docker/client"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
-type kubeConfig struct {
-	APIVersion string `yaml:"apiVersion"`
-	Clusters   []struct {
-		Cluster struct {
-			CertificateAuthorityData string `yaml:"certificate-authority-data"`
-			Server                   string `yaml:"server"`
-		} `yaml:"cluster"`
-		Name string `yaml:"name"`
-	} `yaml:"clusters"`
-	Contexts []struct {
-		Context struct {
-			Cluster string `yaml:"cluster"`
-			User    string `yaml:"user"`
-		} `yaml:"context"`
-		Name string `yaml:"name"`
-	} `yaml:"contexts"`
-	CurrentContext string `yaml:"current-context"`
-	Kind           string `yaml:"kind"`
-	Preferences    struct {
-	} `yaml:"preferences"`
-	Users []struct {
-		Name string `yaml:"name"`
-		User struct {
-			ClientCertificateData string `yaml:"client-certificate-data"`
-			ClientKeyData         string `yaml:"client-key-data"`
-		} `yaml:"user"`
-	} `yaml:"users"`
+// Kubeconfig modes
+const (
+	// KubeConfigModeAuto detects if armada runs inside a container
+	KubeConfigModeAuto = "auto"
+
+	// KubeConfigModeLocal uses kubeconfigs with the api server port published on the host
+	KubeConfigModeLocal = "local"
+
+	// KubeConfigModeContainer uses kubeconfigs with the control plane docker ip
+	KubeConfigModeContainer = "container"
+)
+
+var kubeConfigMode = KubeConfigModeAuto
+
+// SetKubeConfigMode sets which kubeconfig files are used to access the clusters
+func SetKubeConfigMode(mode string) error {
+	switch mode {
+	case KubeConfigModeAuto, KubeConfigModeLocal, KubeConfigModeContainer:
+		kubeConfigMode = mode
+		return nil
+	}
+	return errors.Errorf("invalid kubeconfig mode %q, supported modes: %s, %s, %s", mode, KubeConfigModeLocal, KubeConfigModeContainer, KubeConfigModeAuto)
 }
 
-// PrepareKubeConfigs modifies kubconfig file generated by kind and returns the kubeconfig file path
-func PrepareKubeConfigs(clName string, sourceKubeConfigFilePath, masterIP string) error {
-	var kubeconf kubeConfig
-	currentDir, _ := os.Getwd()
+// KubeConfigRewrite describes the changes applied to a kubeconfig file
+type KubeConfigRewrite struct {
+	// CurrentContext is the new current context name
+	CurrentContext string
 
-	_ = os.MkdirAll(defaults.LocalKubeConfigDir, os.ModePerm)
-	_ = os.MkdirAll(defaults.ContainerKubeConfigDir, os.ModePerm)
-	kindKubeFileName := strings.Join([]string{"kind-config", clName}, "-")
-	newLocalKubeFilePath := filepath.Join(currentDir, defaults.LocalKubeConfigDir, kindKubeFileName)
-	newContainerKubeFilePath := filepath.Join(currentDir, defaults.ContainerKubeConfigDir, kindKubeFileName)
+	// Contexts maps original context names to the new ones
+	Contexts map[string]string
 
-	sourceKubeFile, err := ioutil.ReadFile(sourceKubeConfigFilePath)
+	// Clusters maps original cluster names to the new ones
+	Clusters map[string]string
+
+	// Users maps original user names to the new ones
+	Users map[string]string
+
+	// Servers maps original cluster names to the new api server addresses
+	Servers map[string]string
+}
+
+// LoadKubeConfig parses and validates a kubeconfig
+func LoadKubeConfig(data []byte) (*clientcmdapi.Config, error) {
+	config, err := clientcmd.Load(data)
 	if err != nil {
-		return errors.Wrapf(err, "failed to read kube config %s.", sourceKubeConfigFilePath)
+		return nil, errors.Wrap(err, "failed to parse kube config")
 	}
 
-	err = yaml.Unmarshal(sourceKubeFile, &kubeconf)
-	if err != nil {
-		return errors.Wrapf(err, "failed to read kube config %s.", sourceKubeConfigFilePath)
+	if err := clientcmd.Validate(*config); err != nil {
+		return nil, errors.Wrap(err, "invalid kube config")
 	}
 
-	kubeconf.CurrentContext = clName
-	kubeconf.Contexts[0].Name = clName
-	kubeconf.Contexts[0].Context.Cluster = clName
-	kubeconf.Contexts[0].Context.User = clName
-	kubeconf.Clusters[0].Name = clName
-	kubeconf.Users[0].Name = clName
+	if config.CurrentContext == "" {
+		return nil, errors.New("invalid kube config: current-context is not set")
+	}
+	return config, nil
+}
 
-	d, err := yaml.Marshal(&kubeconf)
-	if err != nil {
-		return errors.Wrapf(err, "failed to marshal kube config.")
+// NewKubeConfigRewrite returns a rewrite that renames the current context, its cluster and user to clName.
+// All the other entries are prefixed with clName to keep them unique across the clusters.
+func NewKubeConfigRewrite(config *clientcmdapi.Config, clName string) *KubeConfigRewrite {
+	rw := &KubeConfigRewrite{
+		CurrentContext: clName,
+		Contexts:       make(map[string]string),
+		Clusters:       make(map[string]string),
+		Users:          make(map[string]string),
+		Servers:        make(map[string]string),
 	}
 
-	err = ioutil.WriteFile(newLocalKubeFilePath, d, 0644)
-	if err != nil {
-		return errors.Wrapf(err, "failed to save kube config %s.", newLocalKubeFilePath)
+	prefixed := func(name string) string {
+		if name == clName || strings.HasPrefix(name, clName+"-") {
+			return name
+		}
+		return clName + "-" + name
+	}
+
+	for name := range config.Contexts {
+		rw.Contexts[name] = prefixed(name)
+	}
+	for name := range config.Clusters {
+		rw.Clusters[name] = prefixed(name)
+	}
+	for name := range config.AuthInfos {
+		rw.Users[name] = prefixed(name)
 	}
 
-	kubeconf.Clusters[0].Cluster.Server = "https://" + masterIP + ":6443"
-	d, err = yaml.Marshal(&kubeconf)
+	current := config.Contexts[config.CurrentContext]
+	rw.Contexts[config.CurrentContext] = clName
+	rw.Clusters[current.Cluster] = clName
+	rw.Users[current.AuthInfo] = clName
+	return rw
+}
+
+// RewriteKubeConfig applies the rewrite to a kubeconfig.
+// The document is modified as is, so the fields that are not modeled by client-go are preserved.
+func RewriteKubeConfig(data []byte, rw *KubeConfigRewrite) ([]byte, error) {
+	config, err := LoadKubeConfig(data)
 	if err != nil {
-		return errors.Wrapf(err, "failed to marshal kube config.")
+		return nil, err
 	}
 
-	err = ioutil.WriteFile(newContainerKubeFilePath, d, 0644)
+	var contexts, clusters, users []string
+	for name := range config.Contexts {
+		contexts = append(contexts, name)
+	}
+	for name := range config.Clusters {
+		clusters = append(clusters, name)
+	}
+	for name := range config.AuthInfos {
+		users = append(users, name)
+	}
+
+	if err := checkUniqueNames(rw.Contexts, contexts); err != nil {
+		return nil, errors.Wrap(err, "contexts")
+	}
+	if err := checkUniqueNames(rw.Clusters, clusters); err != nil {
+		return nil, errors.Wrap(err, "clusters")
+	}
+	if err := checkUniqueNames(rw.Users, users); err != nil {
+		return nil, errors.Wrap(err, "users")
+	}
+
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(err, "failed to parse kube config")
+	}
+
+	for i := range doc {
+		switch doc[i].Key {
+		case "current-context":
+			if rw.CurrentContext != "" {
+				doc[i].Value = rw.CurrentContext
+			}
+		case "clusters":
+			err = forEachNamedEntry(doc[i].Value, "cluster", func(name string, entry yaml.MapSlice) (string, error) {
+				if server, ok := rw.Servers[name]; ok {
+					if !setMapSliceValue(entry, "server", server) {
+						return "", errors.Errorf("cluster %q has no server", name)
+					}
+				}
+				return renamed(rw.Clusters, name), nil
+			})
+		case "contexts":
+			err = forEachNamedEntry(doc[i].Value, "context", func(name string, entry yaml.MapSlice) (string, error) {
+				for j := range entry {
+					switch entry[j].Key {
+					case "cluster":
+						entry[j].Value = renamed(rw.Clusters, fmt.Sprint(entry[j].Value))
+					case "user":
+						entry[j].Value = renamed(rw.Users, fmt.Sprint(entry[j].Value))
+					}
+				}
+				return renamed(rw.Contexts, name), nil
+			})
+		case "users":
+			err = forEachNamedEntry(doc[i].Value, "user", func(name string, entry yaml.MapSlice) (string, error) {
+				return renamed(rw.Users, name), nil
+			})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	d, err := yaml.Marshal(doc)
 	if err != nil {
-		return errors.Wrapf(err, "failed to save kube config %s.", newContainerKubeFilePath)
+		return nil, errors.Wrap(err, "failed to marshal kube config")
+	}
+
+	if _, err := LoadKubeConfig(d); err != nil {
+		return nil, errors.Wrap(err, "rewritten kube config")
+	}
+	return d, nil
+}
+
+// forEachNamedEntry calls fn for every named entry of a kubeconfig list and renames the entry to the returned name
+func forEachNamedEntry(list interface{}, field string, fn func(name string, entry yaml.MapSlice) (string, error)) error {
+	items, ok := list.([]interface{})
+	if !ok {
+		return nil
+	}
+	for _, item := range items {
+		named, ok := item.(yaml.MapSlice)
+		if !ok {
+			return errors.Errorf("invalid %s entry", field)
+		}
+
+		var entry yaml.MapSlice
+		nameIndex := -1
+		for j := range named {
+			switch named[j].Key {
+			case "name":
+				nameIndex = j
+			case field:
+				entry, _ = named[j].Value.(yaml.MapSlice)
+			}
+		}
+		if nameIndex < 0 {
+			return errors.Errorf("%s entry has no name", field)
+		}
+
+		newName, err := fn(fmt.Sprint(named[nameIndex].Value), entry)
+		if err != nil {
+			return err
+		}
+		named[nameIndex].Value = newName
 	}
 	return nil
 }
 
-// GetKubeConfigPath returns different kubeconfig paths for local and docker based runs
-func GetKubeConfigPath(clName string) (string, error) {
-	currentDir, err := os.Getwd()
+// setMapSliceValue sets the value of an existing key and reports if the key was found
+func setMapSliceValue(m yaml.MapSlice, key string, value interface{}) bool {
+	for i := range m {
+		if m[i].Key == key {
+			m[i].Value = value
+			return true
+		}
+	}
+	return false
+}
+
+// renamed returns the new name from the rename map or the original name if it is not mapped
+func renamed(names map[string]string, name string) string {
+	if newName, ok := names[name]; ok && newName != "" {
+		return newName
+	}
+	return name
+}
+
+// checkUniqueNames makes sure that the rename does not merge multiple entries in to one
+func checkUniqueNames(names map[string]string, existing []string) error {
+	seen := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		newName := renamed(names, name)
+		if seen[newName] {
+			return errors.Errorf("duplicate name %q after rename", newName)
+		}
+		seen[newName] = true
+	}
+	return nil
+}
+
+// PrepareKubeConfigs modifies kubconfig file generated by kind and saves the local and container versions of it
+func PrepareKubeConfigs(clName string, sourceKubeConfigFilePath, masterIP string) error {
+	_ = os.MkdirAll(defaults.HomePath(defaults.LocalKubeConfigDir), os.ModePerm)
+	_ = os.MkdirAll(defaults.HomePath(defaults.ContainerKubeConfigDir), os.ModePerm)
+	kindKubeFileName := strings.Join([]string{"kind-config", clName}, "-")
+	newLocalKubeFilePath := defaults.HomePath(defaults.LocalKubeConfigDir, kindKubeFileName)
+	newContainerKubeFilePath := defaults.HomePath(defaults.ContainerKubeConfigDir, kindKubeFileName)
+
+	sourceKubeFile, err := ioutil.ReadFile(sourceKubeConfigFilePath)
 	if err != nil {
-		return "", err
+		return errors.Wrapf(err, "failed to read kube config %s.", sourceKubeConfigFilePath)
 	}
 
-	// Dummy destination
-	conn, err := net.Dial("udp", "1.1.1.1:80")
+	kubeconf, err := LoadKubeConfig(sourceKubeFile)
 	if err != nil {
-		log.Fatal(err)
+		return errors.Wrapf(err, "failed to load kube config %s.", sourceKubeConfigFilePath)
 	}
-	defer conn.Close()
 
-	localAddr := conn.LocalAddr().(*net.UDPAddr)
-	ctx := context.Background()
-	dockerCli, err := dockerclient.NewEnvClient()
+	rw := NewKubeConfigRewrite(kubeconf, clName)
+	d, err := RewriteKubeConfig(sourceKubeFile, rw)
 	if err != nil {
-		return "", err
+		return errors.Wrapf(err, "failed to rewrite kube config %s.", sourceKubeConfigFilePath)
 	}
 
-	networkFilter := filters.NewArgs()
-	networkFilter.Add("driver", "bridge")
-	networks, err := dockerCli.NetworkList(ctx, dockertypes.NetworkListOptions{Filters: networkFilter})
+	err = ioutil.WriteFile(newLocalKubeFilePath, d, 0644)
 	if err != nil {
-		return "", err
+		return errors.Wrapf(err, "failed to save kube config %s.", newLocalKubeFilePath)
 	}
 
-	for _, network := range networks {
-		dockerNet := network.IPAM.Config[0].Subnet
-		_, ipNet, err := net.ParseCIDR(dockerNet)
-		if err != nil {
-			return "", err
-		}
+	for name := range kubeconf.Clusters {
+		rw.Servers[name] = "https://" + masterIP + ":6443"
+	}
+	d, err = RewriteKubeConfig(sourceKubeFile, rw)
+	if err != nil {
+		return errors.Wrapf(err, "failed to rewrite kube config %s.", sourceKubeConfigFilePath)
+	}
 
-		if ipNet.Contains(localAddr.IP) {
-			log.Debugf("Running in a container. Bridge network: %s, ip: %s.	", dockerNet, localAddr.IP)
-			kubeConfigFilePath := filepath.Join(currentDir, defaults.ContainerKubeConfigDir, strings.Join([]string{"kind-config", clName}, "-"))
-			return kubeConfigFilePath, nil
-		}
+	err = ioutil.WriteFile(newContainerKubeFilePath, d, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save kube config %s.", newContainerKubeFilePath)
+	}
+	return nil
+}
+
+// GetKubeConfigPath returns different kubeconfig paths for local and docker based runs
+func GetKubeConfigPath(clName string) (string, error) {
+	kubeConfigDir := defaults.LocalKubeConfigDir
+	if kubeConfigMode == KubeConfigModeContainer || (kubeConfigMode == KubeConfigModeAuto && RunningInContainer()) {
+		kubeConfigDir = defaults.ContainerKubeConfigDir
 	}
-	log.Debugf("Running in a host mode. ip: %s.", localAddr.IP)
-	kubeConfigFilePath := filepath.Join(currentDir, defaults.LocalKubeConfigDir, strings.Join([]string{"kind-config", clName}, "-"))
-	return kubeConfigFilePath, nil
+	log.Debugf("Using %s kubeconfig for %s, mode: %s.", filepath.Base(kubeConfigDir), clName, kubeConfigMode)
+	return defaults.HomePath(kubeConfigDir, strings.Join([]string{"kind-config", clName}, "-")), nil
 }
@@ -14,6 +14,11 @@ import (
 
 var _ = Describe("kubeconfig tests", func() {
 
+	BeforeSuite(func() {
+		err := defaults.SetHome("./output")
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
 	AfterSuite(func() {
 		_ = os.RemoveAll("./output")
 	})
@@ -29,8 +34,8 @@ var _ = Describe("kubeconfig tests", func() {
 
 			configDir := filepath.Join(currentDir, "testdata/kube")
 			kindKubeFileName := strings.Join([]string{"kind-config", cl.Name}, "-")
-			newLocalKubeFilePath := filepath.Join(currentDir, defaults.LocalKubeConfigDir, kindKubeFileName)
-			newContainerKubeFilePath := filepath.Join(currentDir, defaults.ContainerKubeConfigDir, kindKubeFileName)
+			newLocalKubeFilePath := defaults.HomePath(defaults.LocalKubeConfigDir, kindKubeFileName)
+			newContainerKubeFilePath := defaults.HomePath(defaults.ContainerKubeConfigDir, kindKubeFileName)
 			gfs := filepath.Join(configDir, "kubeconfig_source")
 			err = cluster.PrepareKubeConfigs(cl.Name, gfs, "172.17.0.3")
 			Ω(err).ShouldNot(HaveOccurred())
@@ -47,10 +52,108 @@ var _ = Describe("kubeconfig tests", func() {
 			Expect(string(local)).Should(Equal(string(localGolden)))
 			Expect(string(container)).Should(Equal(string(containerGolden)))
 		})
+		It("Should rename all entries and keep the fields unknown to client-go", func() {
+			currentDir, err := os.Getwd()
+			Ω(err).ShouldNot(HaveOccurred())
+
+			configDir := filepath.Join(currentDir, "testdata/kube")
+			source, err := ioutil.ReadFile(filepath.Join(configDir, "kubeconfig_multi_source"))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			kubeconf, err := cluster.LoadKubeConfig(source)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			rw := cluster.NewKubeConfigRewrite(kubeconf, "cl2")
+			rw.Servers["kind-cl2"] = "https://172.17.0.4:6443"
+			rw.Servers["kind-cl2-internal"] = "https://172.17.0.5:6443"
+			actual, err := cluster.RewriteKubeConfig(source, rw)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			golden, err := ioutil.ReadFile(filepath.Join(configDir, "kubeconfig_multi.golden"))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Expect(string(actual)).Should(Equal(string(golden)))
+		})
+		It("Should fail to load kube config with a missing cluster", func() {
+			source := []byte(`apiVersion: v1
+kind: Config
+clusters: []
+contexts:
+- context:
+    cluster: missing
+    user: admin
+  name: admin
+current-context: admin
+users:
+- name: admin
+  user:
+    token: secret
+`)
+			_, err := cluster.LoadKubeConfig(source)
+			Ω(err).Should(HaveOccurred())
+		})
+		It("Should fail to load kube config without a current context", func() {
+			source := []byte(`apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://127.0.0.1:6443
+  name: cl1
+contexts:
+- context:
+    cluster: cl1
+    user: admin
+  name: admin
+users:
+- name: admin
+  user:
+    token: secret
+`)
+			_, err := cluster.LoadKubeConfig(source)
+			Ω(err).Should(HaveOccurred())
+		})
+		It("Should fail to rename two entries to the same name", func() {
+			currentDir, err := os.Getwd()
+			Ω(err).ShouldNot(HaveOccurred())
+
+			source, err := ioutil.ReadFile(filepath.Join(currentDir, "testdata/kube", "kubeconfig_multi_source"))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			kubeconf, err := cluster.LoadKubeConfig(source)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			rw := cluster.NewKubeConfigRewrite(kubeconf, "cl2")
+			rw.Clusters["kind-cl2-internal"] = "cl2"
+			_, err = cluster.RewriteKubeConfig(source, rw)
+			Ω(err).Should(HaveOccurred())
+		})
 		It("Should return correct kubeconfig file path", func() {
 			got, err := cluster.GetKubeConfigPath("cl1")
 			Ω(err).ShouldNot(HaveOccurred())
 			Expect(got).ShouldNot(BeNil())
 		})
+		It("Should return kubeconfig file path for the selected mode", func() {
+			currentDir, err := os.Getwd()
+			Ω(err).ShouldNot(HaveOccurred())
+			defer func() {
+				Ω(cluster.SetKubeConfigMode(cluster.KubeConfigModeAuto)).ShouldNot(HaveOccurred())
+			}()
+
+			err = cluster.SetKubeConfigMode(cluster.KubeConfigModeLocal)
+			Ω(err).ShouldNot(HaveOccurred())
+			got, err := cluster.GetKubeConfigPath("cl1")
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(got).Should(Equal(filepath.Join(currentDir, "output", defaults.LocalKubeConfigDir, "kind-config-cl1")))
+
+			err = cluster.SetKubeConfigMode(cluster.KubeConfigModeContainer)
+			Ω(err).ShouldNot(HaveOccurred())
+			got, err = cluster.GetKubeConfigPath("cl1")
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(got).Should(Equal(filepath.Join(currentDir, "output", defaults.ContainerKubeConfigDir, "kind-config-cl1")))
+		})
+		It("Should return error for unknown kubeconfig mode", func() {
+			err := cluster.SetKubeConfigMode("remote")
+			Ω(err).Should(HaveOccurred())
+		})
 	})
 })
@@ -0,0 +1,234 @@
+package cluster
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	kind "sigs.k8s.io/kind/pkg/cluster"
+)
+
+// Kube proxy modes
+const (
+	KubeProxyModeIPTables = "iptables"
+	KubeProxyModeIPVS     = "ipvs"
+	KubeProxyModeDisabled = "disabled"
+)
+
+// kubeProxyPodsSelector selects the kube-proxy pods of kubeadm clusters
+const kubeProxyPodsSelector = "k8s-app=kube-proxy"
+
+// kubeProxyDeletionTimeout is how long to wait for the kube-proxy pods to terminate
+const kubeProxyDeletionTimeout = 2 * time.Minute
+
+// kubeProxyChains matches the iptables chains created by kube-proxy, kubelet chains like KUBE-FIREWALL and KUBE-MARK-MASQ are kept
+const kubeProxyChains = `KUBE-(SERVICES|EXTERNAL-SERVICES|NODEPORTS|POSTROUTING|FORWARD|PROXY-[A-Z0-9-]+|(SVC|SEP|FW|XLB)-[A-Z0-9]+)`
+
+// kubeProxyRule matches the iptables-save lines declaring, filling or jumping to a kube-proxy chain
+var kubeProxyRule = regexp.MustCompile(`^(:|-A )` + kubeProxyChains + `( |$)|-j ` + kubeProxyChains + `( |$)`)
+
+// ipvsKernelModules are the kernel modules required by kube-proxy in ipvs mode, alternatives are separated by |
+var ipvsKernelModules = []string{"ip_vs", "ip_vs_rr", "ip_vs_wrr", "ip_vs_sh", "nf_conntrack|nf_conntrack_ipv4"}
+
+// ValidateKubeProxyMode normalizes and validates the kube-proxy mode of the cluster
+func ValidateKubeProxyMode(cl *Config) error {
+	switch strings.ToLower(cl.KubeProxyMode) {
+	case "", KubeProxyModeIPTables:
+		cl.KubeProxyMode = ""
+	case KubeProxyModeIPVS:
+		cl.KubeProxyMode = KubeProxyModeIPVS
+		if cl.KubernetesVersion != "" {
+			ver, err := semver.NewVersion(cl.KubernetesVersion)
+			if err != nil {
+				return err
+			}
+			if ver.LessThan(semver.MustParse("1.12.0")) {
+				return errors.Errorf("%q: kube-proxy ipvs mode requires kubernetes 1.12 or newer", cl.Name)
+			}
+		}
+	case KubeProxyModeDisabled:
+		cl.KubeProxyMode = KubeProxyModeDisabled
+	default:
+		return errors.Errorf("%q: unknown kube-proxy mode %q, supported: iptables, ipvs, disabled", cl.Name, cl.KubeProxyMode)
+	}
+	return nil
+}
+
+// GetKubeProxyMode returns the kube-proxy mode of the cluster config
+func GetKubeProxyMode(cl *Config) string {
+	if cl.KubeProxyMode == "" {
+		return KubeProxyModeIPTables
+	}
+	return cl.KubeProxyMode
+}
+
+// CheckIPVSKernelModules returns an error if the kernel modules required by ipvs mode are not loaded, built in or available.
+// Kind nodes share the host kernel, so the modules are checked on the host.
+func CheckIPVSKernelModules() error {
+	release, err := ioutil.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return err
+	}
+
+	modulesDir := filepath.Join("/lib/modules", strings.TrimSpace(string(release)))
+	missing, err := MissingKernelModules("/proc/modules", modulesDir, ipvsKernelModules)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("kernel modules required by kube-proxy ipvs mode are missing: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// MissingKernelModules returns the modules that are not loaded, built in or available in modulesDir.
+// A module can list alternatives separated by |, eg: nf_conntrack|nf_conntrack_ipv4
+func MissingKernelModules(procModules, modulesDir string, modules []string) ([]string, error) {
+	found := map[string]bool{}
+
+	loaded, err := os.Open(procModules)
+	if err != nil {
+		return nil, err
+	}
+	defer loaded.Close()
+
+	scanner := bufio.NewScanner(loaded)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 {
+			found[fields[0]] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// modules.builtin lists the built in modules, modules.dep the loadable ones, eg: kernel/net/netfilter/ipvs/ip_vs.ko:
+	for _, index := range []string{"modules.builtin", "modules.dep"} {
+		content, err := ioutil.ReadFile(filepath.Join(modulesDir, index))
+		if err != nil {
+			log.Debugf("Unable to read kernel modules index: %v.", err)
+			continue
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			path := strings.SplitN(line, ":", 2)[0]
+			name := filepath.Base(path)
+			if i := strings.Index(name, ".ko"); i > 0 {
+				found[strings.Replace(name[:i], "-", "_", -1)] = true
+			}
+		}
+	}
+
+	var missing []string
+	for _, module := range modules {
+		available := false
+		for _, alternative := range strings.Split(module, "|") {
+			available = available || found[alternative]
+		}
+		if !available {
+			missing = append(missing, module)
+		}
+	}
+	return missing, nil
+}
+
+// DisableKubeProxy removes the kube-proxy daemonset and, once its pods are gone, its iptables rules from the cluster nodes
+func DisableKubeProxy(clName string, clientSet kubernetes.Interface, provider *kind.Provider) error {
+	propagation := metav1.DeletePropagationForeground
+	err := clientSet.AppsV1().DaemonSets("kube-system").Delete("kube-proxy", &metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil {
+		return errors.Wrap(err, "deleting kube-proxy daemonset")
+	}
+
+	// kube-proxy pods in their termination grace period would sync the rules again
+	err = WaitForKubeProxyPodsDeleted(clName, clientSet, kubeProxyDeletionTimeout)
+	if err != nil {
+		return err
+	}
+
+	nodeList, err := provider.ListNodes(clName)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodeList {
+		var saved bytes.Buffer
+		if err := node.Command("iptables-save").SetStdout(&saved).Run(); err != nil {
+			return errors.Wrapf(err, "reading iptables rules of %s", node.String())
+		}
+		rules := RemoveKubeProxyRules(saved.String())
+		if err := node.Command("iptables-restore").SetStdin(strings.NewReader(rules)).Run(); err != nil {
+			return errors.Wrapf(err, "removing kube-proxy iptables rules from %s", node.String())
+		}
+	}
+	log.Infof("✔ Kube-proxy was disabled in %s.", clName)
+	return nil
+}
+
+// WaitForKubeProxyPodsDeleted waits up to timeout until no kube-proxy pods are left in the cluster
+func WaitForKubeProxyPodsDeleted(clName string, clientSet kubernetes.Interface, timeout time.Duration) error {
+	remaining := 0
+	err := utilwait.PollImmediate(time.Second, timeout, func() (bool, error) {
+		pods, err := clientSet.CoreV1().Pods("kube-system").List(metav1.ListOptions{LabelSelector: kubeProxyPodsSelector})
+		if err != nil {
+			log.Debugf("%s: unable to list kube-proxy pods: %v", clName, err)
+			return false, nil
+		}
+		remaining = len(pods.Items)
+		return remaining == 0, nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "waiting for %d kube-proxy pods to be deleted in %s", remaining, clName)
+	}
+	return nil
+}
+
+// RemoveKubeProxyRules returns the iptables-save output without the chains and the rules of kube-proxy
+func RemoveKubeProxyRules(rules string) string {
+	var kept []string
+	for _, line := range strings.Split(rules, "\n") {
+		if !kubeProxyRule.MatchString(line) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// GetClusterKubeProxyMode returns the kube-proxy mode of a running cluster
+func GetClusterKubeProxyMode(clientSet kubernetes.Interface) (string, error) {
+	_, err := clientSet.AppsV1().DaemonSets("kube-system").Get("kube-proxy", metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return KubeProxyModeDisabled, nil
+		}
+		return "", err
+	}
+
+	configMap, err := clientSet.CoreV1().ConfigMaps("kube-system").Get("kube-proxy", metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	kubeProxyConfig := struct {
+		Mode string `yaml:"mode"`
+	}{}
+	if err := yaml.Unmarshal([]byte(configMap.Data["config.conf"]), &kubeProxyConfig); err != nil {
+		return "", errors.Wrap(err, "parsing kube-proxy config")
+	}
+	if kubeProxyConfig.Mode != "" {
+		return kubeProxyConfig.Mode, nil
+	}
+	return KubeProxyModeIPTables, nil
+}
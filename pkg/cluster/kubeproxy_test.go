@@ -0,0 +1,173 @@
+package cluster_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/gobuffalo/packr/v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+// nodeIPTablesRules is an iptables-save output of a kind node with kubelet and kube-proxy chains
+const nodeIPTablesRules = `*nat
+:PREROUTING ACCEPT [0:0]
+:OUTPUT ACCEPT [0:0]
+:KUBE-MARK-MASQ - [0:0]
+:KUBE-NODEPORTS - [0:0]
+:KUBE-POSTROUTING - [0:0]
+:KUBE-SEP-6E7XQMQ4RAYOWTTM - [0:0]
+:KUBE-SERVICES - [0:0]
+:KUBE-SVC-NPX46M4PTMTKRN6Y - [0:0]
+-A PREROUTING -m comment --comment "kubernetes service portals" -j KUBE-SERVICES
+-A OUTPUT -m comment --comment "kubernetes service portals" -j KUBE-SERVICES
+-A KUBE-MARK-MASQ -j MARK --set-xmark 0x4000/0x4000
+-A KUBE-POSTROUTING -m mark --mark 0x4000/0x4000 -j MASQUERADE
+-A KUBE-SEP-6E7XQMQ4RAYOWTTM -s 172.17.0.2/32 -j KUBE-MARK-MASQ
+-A KUBE-SERVICES -d 100.1.0.1/32 -p tcp -m tcp --dport 443 -j KUBE-SVC-NPX46M4PTMTKRN6Y
+-A KUBE-SVC-NPX46M4PTMTKRN6Y -j KUBE-SEP-6E7XQMQ4RAYOWTTM
+COMMIT
+*filter
+:INPUT ACCEPT [0:0]
+:KUBE-FIREWALL - [0:0]
+:KUBE-PROXY-CANARY - [0:0]
+-A INPUT -j KUBE-FIREWALL
+-A KUBE-FIREWALL -m mark --mark 0x8000/0x8000 -j DROP
+COMMIT
+`
+
+var _ = Describe("kube-proxy tests", func() {
+
+	box := packr.New("configs", "../../configs")
+
+	Context("Kube-proxy mode", func() {
+		It("Should generate correct kind config for ipvs mode", func() {
+			currentDir, err := os.Getwd()
+			Ω(err).ShouldNot(HaveOccurred())
+
+			cl := &cluster.Config{
+				Cni:                 "flannel",
+				Name:                "ipvs",
+				PodSubnet:           "10.4.0.0/14",
+				ServiceSubnet:       "100.1.0.0/16",
+				DNSDomain:           "cl1.local",
+				KubeAdminAPIVersion: "kubeadm.k8s.io/v1beta2",
+				KubernetesVersion:   "1.16.3",
+				KubeProxyMode:       "IPVS",
+				NumWorkers:          2,
+			}
+			Ω(cluster.ValidateKubeProxyMode(cl)).Should(Succeed())
+
+			configDir := filepath.Join(currentDir, "testdata/kind")
+			gf := filepath.Join(configDir, "kube_proxy_ipvs.golden")
+			configPath, err := cluster.GenerateKindConfig(cl, configDir, box)
+			Ω(err).ShouldNot(HaveOccurred())
+			defer os.RemoveAll(configPath)
+
+			golden, err := ioutil.ReadFile(gf)
+			Ω(err).ShouldNot(HaveOccurred())
+			actual, err := ioutil.ReadFile(configPath)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Expect(string(actual)).Should(Equal(string(golden)))
+		})
+		It("Should validate kube-proxy modes", func() {
+			for mode, expected := range map[string]string{
+				"":         "iptables",
+				"iptables": "iptables",
+				"ipvs":     "ipvs",
+				"Disabled": "disabled",
+			} {
+				cl := &cluster.Config{KubeProxyMode: mode, KubernetesVersion: "1.16.3"}
+				Ω(cluster.ValidateKubeProxyMode(cl)).Should(Succeed())
+				Expect(cluster.GetKubeProxyMode(cl)).Should(Equal(expected))
+			}
+
+			Ω(cluster.ValidateKubeProxyMode(&cluster.Config{KubeProxyMode: "userspace"})).ShouldNot(Succeed())
+			Ω(cluster.ValidateKubeProxyMode(&cluster.Config{KubeProxyMode: "ipvs", KubernetesVersion: "1.11.10"})).ShouldNot(Succeed())
+		})
+		It("Should return the missing kernel modules", func() {
+			kernelDir := filepath.Join("testdata", "kernel")
+			missing, err := cluster.MissingKernelModules(filepath.Join(kernelDir, "modules"), filepath.Join(kernelDir, "5.4.0"),
+				[]string{"ip_vs", "ip_vs_rr", "ip_vs_wrr", "ip_vs_sh", "nf_conntrack|nf_conntrack_ipv4"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(missing).Should(Equal([]string{"ip_vs_sh"}))
+		})
+		It("Should return the kube-proxy mode and status of a running cluster", func() {
+			clientSet := testclient.NewSimpleClientset(
+				&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy", Namespace: "kube-system"}},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy", Namespace: "kube-system"},
+					Data:       map[string]string{"config.conf": "ipvs:\n  scheduler: \"\"\nmode: \"ipvs\"\n"},
+				},
+				&corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: "cl1-control-plane"},
+					Status: corev1.NodeStatus{
+						Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+						NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: "v1.16.3"},
+					},
+				},
+				&corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: "cl1-worker"},
+					Status: corev1.NodeStatus{
+						Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionFalse}},
+						NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: "v1.16.3"},
+					},
+				},
+			)
+
+			status, err := cluster.GetClusterStatus("cl1", clientSet)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(status).Should(Equal(&cluster.Status{
+				Name:              "cl1",
+				State:             cluster.StateRunning,
+				Nodes:             2,
+				ReadyNodes:        1,
+				KubernetesVersion: "v1.16.3",
+				KubeProxyMode:     "ipvs",
+			}))
+		})
+		It("Should remove only the kube-proxy iptables chains", func() {
+			Expect(cluster.RemoveKubeProxyRules(nodeIPTablesRules)).Should(Equal(`*nat
+:PREROUTING ACCEPT [0:0]
+:OUTPUT ACCEPT [0:0]
+:KUBE-MARK-MASQ - [0:0]
+-A KUBE-MARK-MASQ -j MARK --set-xmark 0x4000/0x4000
+COMMIT
+*filter
+:INPUT ACCEPT [0:0]
+:KUBE-FIREWALL - [0:0]
+-A INPUT -j KUBE-FIREWALL
+-A KUBE-FIREWALL -m mark --mark 0x8000/0x8000 -j DROP
+COMMIT
+`))
+		})
+		It("Should wait for the kube-proxy pods to be deleted", func() {
+			kubeProxyPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy-x7k2p", Namespace: "kube-system",
+				Labels: map[string]string{"k8s-app": "kube-proxy"}}}
+			clientSet := testclient.NewSimpleClientset(kubeProxyPod)
+
+			err := cluster.WaitForKubeProxyPodsDeleted("cl1", clientSet, time.Second)
+			Ω(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("1 kube-proxy pods"))
+
+			go func() {
+				time.Sleep(time.Second)
+				_ = clientSet.CoreV1().Pods("kube-system").Delete(kubeProxyPod.Name, &metav1.DeleteOptions{})
+			}()
+			Ω(cluster.WaitForKubeProxyPodsDeleted("cl1", clientSet, 10*time.Second)).Should(Succeed())
+		})
+		It("Should return disabled kube-proxy mode without the daemonset", func() {
+			mode, err := cluster.GetClusterKubeProxyMode(testclient.NewSimpleClientset())
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(mode).Should(Equal(cluster.KubeProxyModeDisabled))
+		})
+	})
+})
@@ -0,0 +1,133 @@
+package cluster
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// cniDaemonSets are the kube-system daemon set name prefixes the deployed cni is detected by
+var cniDaemonSets = map[string]string{
+	"calico-node":     "calico",
+	"kube-flannel-ds": "flannel",
+	"weave-net":       "weave",
+	"kindnet":         "kindnet",
+}
+
+// kubeadmClusterConfiguration is the part of the kubeadm ClusterConfiguration armada uses
+type kubeadmClusterConfiguration struct {
+	APIVersion        string `yaml:"apiVersion"`
+	KubernetesVersion string `yaml:"kubernetesVersion"`
+	Networking        struct {
+		PodSubnet     string `yaml:"podSubnet"`
+		ServiceSubnet string `yaml:"serviceSubnet"`
+		DNSDomain     string `yaml:"dnsDomain"`
+	} `yaml:"networking"`
+}
+
+// GetClusterConfig returns the config of a running cluster from its kubeadm config, nodes and deployed addons
+func GetClusterConfig(clName string, clientSet kubernetes.Interface) (*Config, error) {
+	configMap, err := clientSet.CoreV1().ConfigMaps("kube-system").Get("kubeadm-config", metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "getting kubeadm config")
+	}
+
+	kubeadmConfig := kubeadmClusterConfiguration{}
+	if err := yaml.Unmarshal([]byte(configMap.Data["ClusterConfiguration"]), &kubeadmConfig); err != nil {
+		return nil, errors.Wrap(err, "parsing kubeadm config")
+	}
+
+	cl := &Config{
+		Name:                clName,
+		PodSubnet:           kubeadmConfig.Networking.PodSubnet,
+		ServiceSubnet:       kubeadmConfig.Networking.ServiceSubnet,
+		DNSDomain:           kubeadmConfig.Networking.DNSDomain,
+		KubeAdminAPIVersion: kubeadmConfig.APIVersion,
+		KubernetesVersion:   strings.TrimPrefix(kubeadmConfig.KubernetesVersion, "v"),
+	}
+
+	nodeList, err := clientSet.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing nodes")
+	}
+	for _, node := range nodeList.Items {
+		if _, ok := node.Labels["node-role.kubernetes.io/master"]; !ok {
+			cl.NumWorkers++
+		}
+	}
+
+	daemonSets, err := clientSet.AppsV1().DaemonSets("kube-system").List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing daemon sets")
+	}
+	for _, daemonSet := range daemonSets.Items {
+		for prefix, cni := range cniDaemonSets {
+			if strings.HasPrefix(daemonSet.Name, prefix) {
+				cl.Cni = cni
+			}
+		}
+	}
+
+	_, err = clientSet.AppsV1().Deployments("kube-system").Get("tiller-deploy", metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.Wrap(err, "getting tiller deployment")
+	}
+	cl.Tiller = err == nil
+
+	kubeProxyMode, err := GetClusterKubeProxyMode(clientSet)
+	if err != nil {
+		return nil, err
+	}
+	if kubeProxyMode != KubeProxyModeIPTables {
+		cl.KubeProxyMode = kubeProxyMode
+	}
+	return cl, nil
+}
+
+// GetClusterConfigs returns the configs of the target clusters and of all the other known clusters that are reachable
+func GetClusterConfigs(targetClusters []string) (map[string]*Config, error) {
+	knownClusters, err := GetTargetClusterNames(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := map[string]*Config{}
+	for _, clName := range append(knownClusters, targetClusters...) {
+		if _, ok := configs[clName]; ok {
+			continue
+		}
+
+		var cl *Config
+		clientSet, err := GetClientSet(clName)
+		if err == nil {
+			cl, err = GetClusterConfig(clName, clientSet)
+		}
+		if err == nil {
+			configs[clName] = cl
+		} else {
+			for _, target := range targetClusters {
+				if target == clName {
+					return nil, errors.Wrapf(err, "%s", clName)
+				}
+			}
+			log.Warnf("Skipping %s in the peers of the clusters: %v", clName, err)
+		}
+	}
+	return configs, nil
+}
+
+// SortConfigs returns the configs sorted by cluster name
+func SortConfigs(configs map[string]*Config) []*Config {
+	var sorted []*Config
+	for _, cl := range configs {
+		sorted = append(sorted, cl)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
@@ -0,0 +1,59 @@
+package cluster
+
+import (
+	"bytes"
+	"path/filepath"
+	"text/template"
+
+	"github.com/dimaunx/armada/pkg/deploy"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ManifestData is the data user manifests are rendered with, eg: {{.PodSubnet}} or {{range .Peers}}{{.Name}}{{end}}
+type ManifestData struct {
+	*Config
+
+	// Peers are the configs of the other clusters
+	Peers []*Config
+}
+
+// RenderManifest renders the manifest go template for the cluster
+func RenderManifest(name, manifest string, cl *Config, peers []*Config) (string, error) {
+	t, err := template.New(name).Funcs(template.FuncMap{"iterate": iterate}).Option("missingkey=error").Parse(manifest)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing %s", name)
+	}
+
+	data := ManifestData{Config: cl}
+	for _, peer := range peers {
+		if peer.Name != cl.Name {
+			data.Peers = append(data.Peers, peer)
+		}
+	}
+
+	var rendered bytes.Buffer
+	if err := t.Execute(&rendered, data); err != nil {
+		return "", errors.Wrapf(err, "rendering %s for %s", name, cl.Name)
+	}
+	return rendered.String(), nil
+}
+
+// RenderManifestObjects renders the manifest files for the cluster and decodes them into a single object list, so the
+// objects of all the files are applied in dependency order or removed together
+func RenderManifestObjects(cl *Config, peers []*Config, manifests []deploy.ManifestFile) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	for _, manifest := range manifests {
+		rendered, err := RenderManifest(filepath.Base(manifest.Path), manifest.Content, cl, peers)
+		if err != nil {
+			return nil, err
+		}
+
+		manifestObjects, err := deploy.Decode(rendered)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s", manifest.Path)
+		}
+		objects = append(objects, manifestObjects...)
+	}
+	return objects, nil
+}
@@ -0,0 +1,114 @@
+package cluster_test
+
+import (
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/dimaunx/armada/pkg/deploy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+const kubeadmClusterConfiguration = `apiServer:
+  certSANs:
+  - localhost
+apiVersion: kubeadm.k8s.io/v1beta2
+clusterName: cluster2
+dns:
+  type: CoreDNS
+kind: ClusterConfiguration
+kubernetesVersion: v1.16.3
+networking:
+  dnsDomain: cluster2.local
+  podSubnet: 10.8.0.0/14
+  serviceSubnet: 100.2.0.0/16
+`
+
+const peersManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{.Name}}-peers
+data:
+  cni: {{.Cni}}
+  cidrs: "{{.PodSubnet}},{{.ServiceSubnet}}"
+{{- range .Peers}}
+  {{.Name}}: "{{.PodSubnet}},{{.ServiceSubnet}}"
+{{- end}}
+`
+
+var _ = Describe("manifests tests", func() {
+	Context("Cluster config", func() {
+		It("Should return the config of a running cluster", func() {
+			clientSet := testclient.NewSimpleClientset(
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "kubeadm-config", Namespace: "kube-system"},
+					Data:       map[string]string{"ClusterConfiguration": kubeadmClusterConfiguration},
+				},
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cluster2-control-plane", Labels: map[string]string{"node-role.kubernetes.io/master": ""}}},
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cluster2-worker"}},
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cluster2-worker2"}},
+				&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "kube-flannel-ds-amd64", Namespace: "kube-system"}},
+			)
+
+			cl, err := cluster.GetClusterConfig("cluster2", clientSet)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(cl).Should(Equal(&cluster.Config{
+				Name:                "cluster2",
+				Cni:                 "flannel",
+				PodSubnet:           "10.8.0.0/14",
+				ServiceSubnet:       "100.2.0.0/16",
+				DNSDomain:           "cluster2.local",
+				KubeAdminAPIVersion: "kubeadm.k8s.io/v1beta2",
+				KubernetesVersion:   "1.16.3",
+				NumWorkers:          2,
+				KubeProxyMode:       cluster.KubeProxyModeDisabled,
+			}))
+		})
+		It("Should fail without the kubeadm config", func() {
+			_, err := cluster.GetClusterConfig("cluster2", testclient.NewSimpleClientset())
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+	Context("Manifest templates", func() {
+		clusters := []*cluster.Config{
+			{Name: "cluster1", Cni: "weave", PodSubnet: "10.4.0.0/14", ServiceSubnet: "100.1.0.0/16"},
+			{Name: "cluster2", Cni: "weave", PodSubnet: "10.8.0.0/14", ServiceSubnet: "100.2.0.0/16"},
+			{Name: "cluster3", Cni: "weave", PodSubnet: "10.12.0.0/14", ServiceSubnet: "100.3.0.0/16"},
+		}
+		It("Should render the manifest with the cluster config and its peers", func() {
+			rendered, err := cluster.RenderManifest("peers.yaml", peersManifest, clusters[1], clusters)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(rendered).Should(Equal(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cluster2-peers
+data:
+  cni: weave
+  cidrs: "10.8.0.0/14,100.2.0.0/16"
+  cluster1: "10.4.0.0/14,100.1.0.0/16"
+  cluster3: "10.12.0.0/14,100.3.0.0/16"
+`))
+		})
+		It("Should render the manifest files into a single object list", func() {
+			manifests := []deploy.ManifestFile{
+				{Path: "manifests/crd.yaml", Content: "apiVersion: apiextensions.k8s.io/v1beta1\nkind: CustomResourceDefinition\nmetadata:\n  name: demos.example.com\n"},
+				{Path: "manifests/peers.yaml", Content: peersManifest},
+			}
+			objects, err := cluster.RenderManifestObjects(clusters[0], clusters, manifests)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(objects).Should(HaveLen(2))
+			Expect(objects[0].GetKind()).Should(Equal("CustomResourceDefinition"))
+			Expect(objects[1].GetName()).Should(Equal("cluster1-peers"))
+
+			manifests = append(manifests, deploy.ManifestFile{Path: "manifests/bad.yaml", Content: "name: {{.Unknown}}"})
+			_, err = cluster.RenderManifestObjects(clusters[0], clusters, manifests)
+			Ω(err).Should(HaveOccurred())
+		})
+		It("Should fail to render unknown fields", func() {
+			_, err := cluster.RenderManifest("bad.yaml", "name: {{.Unknown}}", clusters[0], clusters)
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,123 @@
+package cluster
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dimaunx/armada/pkg/wait"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// addons are the objects of the addons armada deploys by name
+var addons = map[string]string{
+	"tiller":            "deployments/kube-system/tiller-deploy",
+	"nginx-demo":        "daemonsets/default/nginx-demo",
+	"netshoot":          "daemonsets/default/netshoot",
+	"netshoot-host-net": "daemonsets/default/netshoot-host-net",
+}
+
+// nodesPollInterval is how often the nodes are listed while waiting for them to register
+const nodesPollInterval = 2 * time.Second
+
+// WaitForNodes waits up to timeout for the expected number of nodes to register and returns their names. API errors are
+// retried, eg: the API server of a cluster that was just created refusing connections.
+func WaitForNodes(clName string, clientSet kubernetes.Interface, expectedNodes int, timeout time.Duration) ([]string, error) {
+	var nodeNames []string
+	var lastErr error
+	err := utilwait.PollImmediate(nodesPollInterval, timeout, func() (bool, error) {
+		nodeList, err := clientSet.CoreV1().Nodes().List(metav1.ListOptions{})
+		if err != nil {
+			lastErr = errors.Wrap(err, "listing nodes")
+			log.Debugf("%s: %v, retrying.", clName, lastErr)
+			return false, nil
+		}
+		if len(nodeList.Items) < expectedNodes {
+			lastErr = errors.Errorf("%d of %d nodes registered", len(nodeList.Items), expectedNodes)
+			log.Debugf("%s: %v.", clName, lastErr)
+			return false, nil
+		}
+
+		nodeNames = nil
+		for _, node := range nodeList.Items {
+			nodeNames = append(nodeNames, node.Name)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(lastErr, "timed out after %v waiting for the nodes of %s", timeout, clName)
+	}
+	return nodeNames, nil
+}
+
+// WaitForReady waits up to timeout for the expected number of nodes to register, then for the objects, eg: the CNI and
+// the addons, and for the cluster nodes and coredns to be ready
+func WaitForReady(clName string, clientSet kubernetes.Interface, client dynamic.Interface, mapper meta.RESTMapper, objects []*unstructured.Unstructured, expectedNodes int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	nodeNames, err := WaitForNodes(clName, clientSet, expectedNodes, timeout)
+	if err != nil {
+		return err
+	}
+
+	for _, nodeName := range nodeNames {
+		objects = append(objects, wait.ObjectRef("v1", "Node", "", nodeName))
+	}
+	objects = append(objects, wait.ObjectRef("apps/v1", "Deployment", "kube-system", "coredns"))
+	return wait.ForObjectsReady(clName, clientSet, client, mapper, objects, time.Until(deadline))
+}
+
+// CniObjects returns the daemon sets of the CNI deployed to the cluster
+func CniObjects(clientSet kubernetes.Interface) ([]*unstructured.Unstructured, error) {
+	daemonSets, err := clientSet.AppsV1().DaemonSets("kube-system").List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing daemon sets")
+	}
+
+	var objects []*unstructured.Unstructured
+	for _, daemonSet := range daemonSets.Items {
+		for prefix := range cniDaemonSets {
+			if strings.HasPrefix(daemonSet.Name, prefix) {
+				objects = append(objects, wait.ObjectRef("apps/v1", "DaemonSet", daemonSet.Namespace, daemonSet.Name))
+			}
+		}
+	}
+	return objects, nil
+}
+
+// AddonObject returns the object of an addon, either an addon armada deploys, eg: nginx-demo, or a
+// <resource>/<namespace>/<name> reference, eg: deployments/kube-system/metrics-server
+func AddonObject(mapper meta.RESTMapper, addon string) (*unstructured.Unstructured, error) {
+	ref := addon
+	if known, ok := addons[addon]; ok {
+		ref = known
+	}
+
+	parts := strings.Split(ref, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return nil, errors.Errorf("unknown addon %q, expected one of %s or <resource>/<namespace>/<name>", addon, strings.Join(AddonNames(), ", "))
+	}
+
+	gvk, err := mapper.KindFor(schema.GroupVersionResource{Resource: parts[0]})
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolving addon %q", addon)
+	}
+	return wait.ObjectRef(gvk.GroupVersion().String(), gvk.Kind, parts[1], parts[2]), nil
+}
+
+// AddonNames returns the names of the addons armada deploys
+func AddonNames() []string {
+	var names []string
+	for name := range addons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,128 @@
+package cluster_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dimaunx/armada/pkg/cluster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// toUnstructured converts a typed object with its type meta to an unstructured object
+func toUnstructured(obj runtime.Object, apiVersion, kind string) *unstructured.Unstructured {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	Ω(err).ShouldNot(HaveOccurred())
+	result := &unstructured.Unstructured{Object: content}
+	result.SetAPIVersion(apiVersion)
+	result.SetKind(kind)
+	return result
+}
+
+var _ = Describe("Cluster readiness", func() {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("DaemonSet"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Node"), meta.RESTScopeRoot)
+
+	replicas := int32(1)
+	coredns := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, ReadyReplicas: 1},
+	}
+	calico := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "calico-node", Namespace: "kube-system"},
+		Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 2, UpdatedNumberScheduled: 2, NumberReady: 2},
+	}
+	node := func(name string, ready corev1.ConditionStatus) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}}},
+		}
+	}
+
+	It("Should resolve addons by name and by reference", func() {
+		obj, err := cluster.AddonObject(mapper, "nginx-demo")
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(obj.GetKind()).Should(Equal("DaemonSet"))
+		Expect(obj.GetNamespace()).Should(Equal("default"))
+		Expect(obj.GetName()).Should(Equal("nginx-demo"))
+
+		obj, err = cluster.AddonObject(mapper, "deployments/kube-system/metrics-server")
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(obj.GetAPIVersion()).Should(Equal("apps/v1"))
+		Expect(obj.GetKind()).Should(Equal("Deployment"))
+
+		_, err = cluster.AddonObject(mapper, "metrics-server")
+		Ω(err).Should(HaveOccurred())
+	})
+	It("Should find the CNI daemon sets", func() {
+		clientSet := testclient.NewSimpleClientset(calico, &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy", Namespace: "kube-system"}})
+
+		objects, err := cluster.CniObjects(clientSet)
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(objects).Should(HaveLen(1))
+		Expect(objects[0].GetName()).Should(Equal("calico-node"))
+	})
+	It("Should wait for the nodes, the CNI and coredns", func() {
+		nodes := []*corev1.Node{node("cl1-control-plane", corev1.ConditionTrue), node("cl1-worker", corev1.ConditionTrue)}
+		clientSet := testclient.NewSimpleClientset(calico, coredns, nodes[0], nodes[1])
+		client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(),
+			toUnstructured(calico, "apps/v1", "DaemonSet"),
+			toUnstructured(coredns, "apps/v1", "Deployment"),
+			toUnstructured(nodes[0], "v1", "Node"),
+			toUnstructured(nodes[1], "v1", "Node"),
+		)
+
+		objects, err := cluster.CniObjects(clientSet)
+		Ω(err).ShouldNot(HaveOccurred())
+		err = cluster.WaitForReady("cl1", clientSet, client, mapper, objects, 2, 10*time.Second)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+	It("Should wait for all the nodes to register and retry api errors", func() {
+		clientSet := testclient.NewSimpleClientset(node("cl1-control-plane", corev1.ConditionTrue))
+		failures := 0
+		clientSet.PrependReactor("list", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if failures < 2 {
+				failures++
+				return true, nil, errors.New("connection refused")
+			}
+			return false, nil, nil
+		})
+
+		_, err := cluster.WaitForNodes("cl1", clientSet, 2, 5*time.Second)
+		Ω(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("1 of 2 nodes registered"))
+		Expect(failures).Should(Equal(2))
+
+		go func() {
+			time.Sleep(time.Second)
+			_, _ = clientSet.CoreV1().Nodes().Create(node("cl1-worker", corev1.ConditionFalse))
+		}()
+		nodeNames, err := cluster.WaitForNodes("cl1", clientSet, 2, 10*time.Second)
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(nodeNames).Should(ConsistOf("cl1-control-plane", "cl1-worker"))
+	})
+	It("Should time out if a node is not ready", func() {
+		worker := node("cl1-worker", corev1.ConditionFalse)
+		clientSet := testclient.NewSimpleClientset(coredns, worker)
+		client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(),
+			toUnstructured(coredns, "apps/v1", "Deployment"),
+			toUnstructured(worker, "v1", "Node"),
+		)
+
+		err := cluster.WaitForReady("cl1", clientSet, client, mapper, nil, 1, time.Second)
+		Ω(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("Node cl1-worker"))
+	})
+})
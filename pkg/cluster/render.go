@@ -0,0 +1,183 @@
+package cluster
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dimaunx/armada/pkg/deploy"
+	"github.com/gobuffalo/packr/v2"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Manifest is a rendered manifest that is applied to a cluster after creation
+type Manifest struct {
+	// Name is the manifest name, also used as the rendered file name
+	Name string
+
+	// Content is the rendered manifest
+	Content string
+
+	// Crd if the manifest contains custom resource definitions
+	Crd bool
+}
+
+// RenderManifests returns the cni and addon manifests of the cluster in the order they are applied
+func RenderManifests(cl *Config, box *packr.Box) ([]Manifest, error) {
+	manifests, err := RenderCniManifests(cl, box)
+	if err != nil {
+		return nil, err
+	}
+
+	if cl.Tiller {
+		tillerDeploymentFile, err := box.Resolve("helm/tiller-deployment.yaml")
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, Manifest{Name: "tiller-deployment", Content: tillerDeploymentFile.String()})
+	}
+	return manifests, nil
+}
+
+// RenderCniManifests returns the cni manifests of the cluster, none for kindnet which kind deploys itself
+func RenderCniManifests(cl *Config, box *packr.Box) ([]Manifest, error) {
+	var manifests []Manifest
+	switch cl.Cni {
+	case "calico":
+		calicoCrdFile, err := GenerateCalicoCrdFile(cl, box)
+		if err != nil {
+			return nil, err
+		}
+
+		calicoDeploymentFile, err := GenerateCalicoDeploymentFile(cl, box)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests,
+			Manifest{Name: "calico-crd", Content: calicoCrdFile, Crd: true},
+			Manifest{Name: "calico-daemonset", Content: calicoDeploymentFile},
+		)
+	case "flannel":
+		flannelDeploymentFile, err := GenerateFlannelDeploymentFile(cl, box)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, Manifest{Name: "flannel-daemonset", Content: flannelDeploymentFile})
+	case "weave":
+		weaveDeploymentFile, err := GenerateWeaveDeploymentFile(cl, box)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, Manifest{Name: "weave-daemonset", Content: weaveDeploymentFile})
+	}
+	return manifests, nil
+}
+
+// ManifestImages returns the container images of the cni and addon manifests of the cluster
+func ManifestImages(cl *Config, box *packr.Box) ([]string, error) {
+	manifests, err := RenderManifests(cl, box)
+	if err != nil {
+		return nil, err
+	}
+	return containerImages(manifests)
+}
+
+// CniImages returns the container images of the cni manifests of the cluster
+func CniImages(cl *Config, box *packr.Box) ([]string, error) {
+	manifests, err := RenderCniManifests(cl, box)
+	if err != nil {
+		return nil, err
+	}
+	return containerImages(manifests)
+}
+
+// containerImages returns the container images of the manifests
+func containerImages(manifests []Manifest) ([]string, error) {
+	var objects []*unstructured.Unstructured
+	for _, manifest := range manifests {
+		manifestObjects, err := deploy.Decode(manifest.Content)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s resources", manifest.Name)
+		}
+		objects = append(objects, manifestObjects...)
+	}
+	return deploy.ContainerImages(objects), nil
+}
+
+// Render writes the kind config and the manifests of the cluster to renderDir/<cluster name> without creating the cluster
+func Render(cl *Config, box *packr.Box, renderDir string) error {
+	clusterDir := filepath.Join(renderDir, cl.Name)
+	if err := os.RemoveAll(clusterDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(clusterDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	if _, err := GenerateKindConfig(cl, clusterDir, box); err != nil {
+		return err
+	}
+
+	manifests, err := RenderManifests(cl, box)
+	if err != nil {
+		return err
+	}
+
+	for i, manifest := range manifests {
+		manifestFilePath := filepath.Join(clusterDir, fmt.Sprintf("%02d-%s.yaml", i+1, manifest.Name))
+		if err := ioutil.WriteFile(manifestFilePath, []byte(manifest.Content), 0644); err != nil {
+			return err
+		}
+	}
+	log.Debugf("Cluster %q rendered to %s.", cl.Name, clusterDir)
+	return nil
+}
+
+// ReadRenderedManifests returns the manifests rendered for the cluster to renderDir/<cluster name>, without the kind config
+func ReadRenderedManifests(renderDir, clName string) ([]deploy.ManifestFile, error) {
+	manifests, err := deploy.ReadManifestFiles(filepath.Join(renderDir, clName))
+	if err != nil {
+		return nil, err
+	}
+
+	var rendered []deploy.ManifestFile
+	for _, manifest := range manifests {
+		if !strings.HasPrefix(filepath.Base(manifest.Path), "kind-config-") {
+			rendered = append(rendered, manifest)
+		}
+	}
+	return rendered, nil
+}
+
+// WriteRenderSummary writes the names, images and allocated cidrs of the clusters as a table
+func WriteRenderSummary(w io.Writer, clusters []*Config) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tKUBERNETES\tIMAGE\tCNI\tKUBE-PROXY\tPOD CIDR\tSERVICE CIDR\tDNS DOMAIN\tWORKERS\tADDONS")
+	for _, cl := range clusters {
+		nodeImageName := cl.NodeImageName
+		if nodeImageName == "" {
+			nodeImageName = "default"
+		}
+
+		var addons []string
+		if cl.Tiller {
+			addons = append(addons, "tiller")
+		}
+		if len(addons) == 0 {
+			addons = append(addons, "-")
+		}
+		cni := cl.Cni
+		if cl.CniVersion != "" {
+			cni += "@" + cl.CniVersion
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n", cl.Name, cl.KubernetesVersion, nodeImageName, cni, GetKubeProxyMode(cl),
+			cl.PodSubnet, cl.ServiceSubnet, cl.DNSDomain, cl.NumWorkers, strings.Join(addons, ","))
+	}
+	return tw.Flush()
+}
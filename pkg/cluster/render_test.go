@@ -0,0 +1,119 @@
+package cluster_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	createclustercmd "github.com/dimaunx/armada/cmd/armada/create/cluster"
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/gobuffalo/packr/v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("render tests", func() {
+
+	box := packr.New("configs", "../../configs")
+
+	Context("Dry run rendering", func() {
+		It("Should return cni and addon manifests in apply order", func() {
+			cl, err := cluster.PopulateConfig(1, "kindest/node:v1.16.3", "calico", true, true, false, 0)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			manifests, err := cluster.RenderManifests(cl, box)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			var names []string
+			for _, manifest := range manifests {
+				names = append(names, manifest.Name)
+			}
+			Expect(names).Should(Equal([]string{"calico-crd", "calico-daemonset", "tiller-deployment"}))
+			Expect(manifests[0].Crd).Should(BeTrue())
+
+			calicoDeploymentFile, err := cluster.GenerateCalicoDeploymentFile(cl, box)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(manifests[1].Content).Should(Equal(calicoDeploymentFile))
+		})
+		It("Should return the container images of the cni and addon manifests", func() {
+			cl, err := cluster.PopulateConfig(1, "kindest/node:v1.16.3", "calico", true, true, false, 0)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			images, err := cluster.ManifestImages(cl, box)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(images).Should(Equal([]string{
+				"calico/cni:v3.9.3",
+				"calico/kube-controllers:v3.9.3",
+				"calico/node:v3.9.3",
+				"calico/pod2daemon-flexvol:v3.9.3",
+				"gcr.io/kubernetes-helm/tiller:v2.15.0",
+			}))
+		})
+		It("Should return only the images of the cni manifests for side loading", func() {
+			cl, err := cluster.PopulateConfig(1, "kindest/node:v1.16.3", "calico", true, true, false, 0)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			images, err := cluster.CniImages(cl, box)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(images).Should(HaveLen(4))
+			Expect(images).ShouldNot(ContainElement("gcr.io/kubernetes-helm/tiller:v2.15.0"))
+
+			cl, err = cluster.PopulateConfig(1, "kindest/node:v1.16.3", "kindnet", true, false, false, 0)
+			Ω(err).ShouldNot(HaveOccurred())
+			images, err = cluster.CniImages(cl, box)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(images).Should(BeEmpty())
+		})
+		It("Should not return manifests for kindnet without addons", func() {
+			cl, err := cluster.PopulateConfig(1, "kindest/node:v1.16.3", "kindnet", true, false, false, 0)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			manifests, err := cluster.RenderManifests(cl, box)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(manifests).Should(BeEmpty())
+		})
+		It("Should render all clusters without docker", func() {
+			renderDir, err := ioutil.TempDir("", "armada-render")
+			Ω(err).ShouldNot(HaveOccurred())
+			defer os.RemoveAll(renderDir)
+
+			flags := &createclustercmd.CreateClusterFlagpole{
+				ImageName:   "kindest/node:v1.15.6",
+				Flannel:     true,
+				NumClusters: 2,
+				DryRun:      true,
+			}
+
+			cluster.SetInspectNodeImages(false)
+			defer cluster.SetInspectNodeImages(true)
+
+			targetClusters, err := createclustercmd.GetTargetClusters(nil, box, flags)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(targetClusters).Should(HaveLen(2))
+
+			err = createclustercmd.RenderClusters(targetClusters, box, renderDir)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			for _, cl := range targetClusters {
+				Expect(filepath.Join(renderDir, cl.Name, "kind-config-"+cl.Name+".yaml")).Should(BeARegularFile())
+				Expect(filepath.Join(renderDir, cl.Name, "01-flannel-daemonset.yaml")).Should(BeARegularFile())
+
+				manifests, err := cluster.ReadRenderedManifests(renderDir, cl.Name)
+				Ω(err).ShouldNot(HaveOccurred())
+				Expect(manifests).Should(HaveLen(1))
+				Expect(manifests[0].Path).Should(Equal(filepath.Join(renderDir, cl.Name, "01-flannel-daemonset.yaml")))
+			}
+
+			var expected bytes.Buffer
+			err = cluster.WriteRenderSummary(&expected, targetClusters)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			summary, err := ioutil.ReadFile(filepath.Join(renderDir, "summary.txt"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(string(summary)).Should(Equal(expected.String()))
+			Expect(string(summary)).Should(ContainSubstring("10.8.0.0/14"))
+			Expect(string(summary)).Should(ContainSubstring("100.2.0.0/16"))
+		})
+	})
+})
@@ -0,0 +1,125 @@
+package cluster
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	dockerclient "github.com/docker/docker/client"
+	log "github.com/sirupsen/logrus"
+)
+
+// containerCgroupMarkers are the cgroup path fragments created by the container runtimes
+var containerCgroupMarkers = []string{"/docker/", "/docker-", "/kubepods", "/containerd", "/lxc/", "/libpod"}
+
+// inContainer is the memoized result of RunningInContainer
+var (
+	inContainer     bool
+	inContainerOnce sync.Once
+)
+
+// RunningInContainer returns true if armada runs inside a container. Only local evidence is used, no network access is required.
+// The check runs once, the docker networks lookup is not repeated for every cluster.
+func RunningInContainer() bool {
+	inContainerOnce.Do(func() {
+		inContainer = runningInContainer()
+	})
+	return inContainer
+}
+
+// runningInContainer checks for /.dockerenv, the cgroups of the container runtimes and the docker bridge networks
+func runningInContainer() bool {
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		log.Debug("Running in a container, /.dockerenv is present.")
+		return true
+	}
+
+	if cgroups, err := ioutil.ReadFile("/proc/1/cgroup"); err == nil {
+		for _, line := range strings.Split(string(cgroups), "\n") {
+			for _, marker := range containerCgroupMarkers {
+				if strings.Contains(line, marker) {
+					log.Debugf("Running in a container, cgroup: %s.", line)
+					return true
+				}
+			}
+		}
+	}
+
+	attached, err := attachedToDockerNetwork()
+	if err != nil {
+		log.Debugf("Unable to match local interfaces against docker networks: %v.", err)
+		return false
+	}
+	return attached
+}
+
+// attachedToDockerNetwork returns true if one of the local interfaces is a member of a docker bridge network.
+// The bridge gateway address is skipped, since it belongs to the docker host.
+func attachedToDockerNetwork() (bool, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false, err
+	}
+
+	var localIPs []net.IP
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
+			localIPs = append(localIPs, ipNet.IP)
+		}
+	}
+	if len(localIPs) == 0 {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dockerCli, err := dockerclient.NewEnvClient()
+	if err != nil {
+		return false, err
+	}
+	defer dockerCli.Close()
+
+	networkFilter := filters.NewArgs()
+	networkFilter.Add("driver", "bridge")
+	networks, err := dockerCli.NetworkList(ctx, dockertypes.NetworkListOptions{Filters: networkFilter})
+	if err != nil {
+		return false, err
+	}
+
+	for _, network := range networks {
+		for _, ipamConfig := range network.IPAM.Config {
+			_, ipNet, err := net.ParseCIDR(ipamConfig.Subnet)
+			if err != nil {
+				continue
+			}
+
+			gateway := net.ParseIP(ipamConfig.Gateway)
+			if gateway == nil {
+				gateway = firstHost(ipNet)
+			}
+
+			for _, ip := range localIPs {
+				if ipNet.Contains(ip) && !ip.Equal(gateway) {
+					log.Debugf("Running in a container. Bridge network: %s, subnet: %s, ip: %s.", network.Name, ipamConfig.Subnet, ip)
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// firstHost returns the first host address of a subnet, docker uses it as a default gateway
+func firstHost(ipNet *net.IPNet) net.IP {
+	ip := make(net.IP, len(ipNet.IP))
+	copy(ip, ipNet.IP)
+	ip[len(ip)-1]++
+	return ip
+}
@@ -0,0 +1,85 @@
+package cluster
+
+import (
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	kind "sigs.k8s.io/kind/pkg/cluster"
+)
+
+// Cluster states
+const (
+	StateRunning     = "running"
+	StateNotFound    = "not found"
+	StateUnreachable = "unreachable"
+)
+
+// Status is the status of a cluster created by armada
+type Status struct {
+	// Name is the cluster name
+	Name string
+
+	// State is running, not found or unreachable
+	State string
+
+	// Nodes is the number of nodes
+	Nodes int
+
+	// ReadyNodes is the number of ready nodes
+	ReadyNodes int
+
+	// KubernetesVersion is the kubelet version of the nodes
+	KubernetesVersion string
+
+	// KubeProxyMode is the kube-proxy mode: iptables, ipvs or disabled
+	KubeProxyMode string
+}
+
+// GetStatus returns the status of the cluster
+func GetStatus(clName string, provider *kind.Provider) (*Status, error) {
+	known, err := IsKnown(clName, provider)
+	if err != nil {
+		return nil, err
+	}
+	if !known {
+		return &Status{Name: clName, State: StateNotFound}, nil
+	}
+
+	clientSet, err := GetClientSet(clName)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := GetClusterStatus(clName, clientSet)
+	if err != nil {
+		return &Status{Name: clName, State: StateUnreachable}, err
+	}
+	return status, nil
+}
+
+// GetClusterStatus returns the status of a running cluster
+func GetClusterStatus(clName string, clientSet kubernetes.Interface) (*Status, error) {
+	status := &Status{Name: clName, State: StateRunning}
+
+	nodeList, err := clientSet.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing nodes")
+	}
+
+	status.Nodes = len(nodeList.Items)
+	for _, node := range nodeList.Items {
+		status.KubernetesVersion = node.Status.NodeInfo.KubeletVersion
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+				status.ReadyNodes++
+			}
+		}
+	}
+
+	status.KubeProxyMode, err = GetClusterKubeProxyMode(clientSet)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading kube-proxy mode")
+	}
+	return status, nil
+}
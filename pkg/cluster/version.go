@@ -0,0 +1,171 @@
+package cluster
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	kinddefaults "sigs.k8s.io/kind/pkg/apis/config/defaults"
+)
+
+// NodeImageVersionLabel is an optional node image label with the kubernetes version, useful for custom built images
+const NodeImageVersionLabel = "io.k8s.sigs.kind.kubernetes-version"
+
+// nodeImageVersionFile is the location of the kubernetes version file in kind node images
+const nodeImageVersionFile = "/kind/version"
+
+// kubeAdminAPIVersions maps the kubernetes versions to the kubeadm config api versions, same as kind does
+var kubeAdminAPIVersions = []struct {
+	constraint string
+	apiVersion string
+}{
+	{"< 1.12", "kubeadm.k8s.io/v1alpha2"},
+	{">= 1.12, < 1.13", "kubeadm.k8s.io/v1alpha3"},
+	{">= 1.13, < 1.15", "kubeadm.k8s.io/v1beta1"},
+	{">= 1.15", "kubeadm.k8s.io/v1beta2"},
+}
+
+// imageVersions caches the versions read from the node images
+var imageVersions sync.Map
+
+// inspectNodeImages if to read the kubernetes version from local node images, disabled for dry runs without docker
+var inspectNodeImages = true
+
+// SetInspectNodeImages enables or disables reading the kubernetes version from local node images
+func SetInspectNodeImages(inspect bool) {
+	inspectNodeImages = inspect
+}
+
+// GetNodeImageVersion returns the kubernetes version of a node image.
+// The version is read from the image if it exists locally, otherwise it is parsed from the image tag.
+func GetNodeImageVersion(image string) (*semver.Version, error) {
+	if image == "" {
+		image = kinddefaults.Image
+	}
+
+	if ver, ok := imageVersions.Load(image); ok {
+		return ver.(*semver.Version), nil
+	}
+
+	var ver *semver.Version
+	var err error
+	if inspectNodeImages {
+		ver, err = ReadNodeImageVersion(context.Background(), image)
+		if err != nil {
+			log.Debugf("Unable to read kubernetes version from image %s: %v. Parsing the image tag.", image, err)
+		}
+	}
+	if ver == nil {
+		ver, err = ParseNodeImageVersion(image)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	imageVersions.Store(image, ver)
+	return ver, nil
+}
+
+// ReadNodeImageVersion reads the kubernetes version of a local node image from its labels or the version file
+func ReadNodeImageVersion(ctx context.Context, image string) (*semver.Version, error) {
+	dockerCli, err := dockerclient.NewEnvClient()
+	if err != nil {
+		return nil, err
+	}
+	defer dockerCli.Close()
+
+	inspect, _, err := dockerCli.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	if inspect.Config != nil {
+		if label, ok := inspect.Config.Labels[NodeImageVersionLabel]; ok {
+			return semver.NewVersion(strings.TrimSpace(label))
+		}
+	}
+
+	resp, err := dockerCli.ContainerCreate(ctx, &container.Config{Image: inspect.ID}, nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = dockerCli.ContainerRemove(ctx, resp.ID, dockertypes.ContainerRemoveOptions{Force: true})
+	}()
+
+	reader, _, err := dockerCli.CopyFromContainer(ctx, resp.ID, nodeImageVersionFile)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	tr := tar.NewReader(reader)
+	if _, err := tr.Next(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s from %s", nodeImageVersionFile, image)
+	}
+
+	content, err := ioutil.ReadAll(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	ver, err := semver.NewVersion(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid kubernetes version in %s of %s", nodeImageVersionFile, image)
+	}
+	log.Debugf("Kubernetes version of %s is %s.", image, ver)
+	return ver, nil
+}
+
+// ParseNodeImageVersion returns the kubernetes version from the node image tag, eg: kindest/node:v1.16.3@sha256:...
+func ParseNodeImageVersion(image string) (*semver.Version, error) {
+	name := strings.SplitN(image, "@", 2)[0]
+	tagIndex := strings.LastIndex(name, ":")
+	if tagIndex < 0 || tagIndex < strings.LastIndex(name, "/") {
+		return nil, errors.Errorf("could not find the kubernetes version of %s, image is not available locally and has no version tag. example of correct image name: kindest/node:v1.15.3", image)
+	}
+
+	ver, err := semver.NewVersion(name[tagIndex+1:])
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not extract kubernetes version from %s tag. example of correct image name: kindest/node:v1.15.3", image)
+	}
+	return ver, nil
+}
+
+// GetKubeAdminAPIVersion returns the kubeadm config api version for the kubernetes version
+func GetKubeAdminAPIVersion(ver *semver.Version) (string, error) {
+	release := releaseVersion(ver)
+	for _, v := range kubeAdminAPIVersions {
+		c, err := semver.NewConstraint(v.constraint)
+		if err != nil {
+			return "", err
+		}
+		if c.Check(release) {
+			return v.apiVersion, nil
+		}
+	}
+	return "", errors.Errorf("no kubeadm api version found for kubernetes %s", ver)
+}
+
+// KubeAdminConfigKind returns the kind of the kubeadm cluster wide config for the kubeadm config api version,
+// v1alpha2 calls it MasterConfiguration
+func KubeAdminConfigKind(apiVersion string) string {
+	if apiVersion == "kubeadm.k8s.io/v1alpha2" {
+		return "MasterConfiguration"
+	}
+	return "ClusterConfiguration"
+}
+
+// releaseVersion strips pre release and metadata, so custom builds like v1.16.3-rc.1-custom match the constraints
+func releaseVersion(ver *semver.Version) *semver.Version {
+	return semver.MustParse(fmt.Sprintf("%d.%d.%d", ver.Major(), ver.Minor(), ver.Patch()))
+}
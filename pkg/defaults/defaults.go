@@ -22,17 +22,26 @@ const (
 	// NumWorkers is the number of worker nodes per cluster
 	NumWorkers = 2
 
-	// KindLogsDir is a default kind log files destination directory
-	KindLogsDir = "output/logs"
+	// KindLogsDir is a default kind log files destination directory relative to armada home
+	KindLogsDir = "logs"
 
-	// KindConfigDir is a default kind config files destination directory
-	KindConfigDir = "output/kind-clusters"
+	// KindConfigDir is a default kind config files destination directory relative to armada home
+	KindConfigDir = "kind-clusters"
 
-	// LocalKubeConfigDir is a default local workstation kubeconfig files destination directory
-	LocalKubeConfigDir = "output/kube-config/local-dev"
+	// LocalKubeConfigDir is a default local workstation kubeconfig files destination directory relative to armada home
+	LocalKubeConfigDir = "kube-config/local-dev"
 
-	// LocalKubeConfigDir is a default  kubeconfig files destination directory if running inside container
-	ContainerKubeConfigDir = "output/kube-config/container"
+	// ContainerKubeConfigDir is a default kubeconfig files destination directory if running inside container relative to armada home
+	ContainerKubeConfigDir = "kube-config/container"
+
+	// RenderDir is a default destination directory for dry run rendered files relative to armada home
+	RenderDir = "render"
+
+	// LegacyOutputDir is the current working directory relative location used by armada before the home directory was introduced
+	LegacyOutputDir = "output"
+
+	// ImageCacheDir is a default directory of the cached image archives relative to armada home
+	ImageCacheDir = "image-cache"
 
 	// WaitDurationResources is a default timeout for waiter functions
 	WaitDurationResources = time.Duration(10) * time.Minute
@@ -0,0 +1,54 @@
+package defaults
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+var home string
+
+// DefaultHome returns the per user armada home directory, $XDG_DATA_HOME/armada or ~/.local/share/armada
+func DefaultHome() (string, error) {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return filepath.Join(dataHome, "armada"), nil
+	}
+
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to find user home directory")
+	}
+	return filepath.Join(userHome, ".local", "share", "armada"), nil
+}
+
+// SetHome sets the armada home directory, where all the clusters state is kept
+func SetHome(dir string) error {
+	if dir == "" {
+		return errors.New("armada home directory can not be empty")
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return errors.Wrapf(err, "invalid armada home directory %q", dir)
+	}
+	home = absDir
+	return nil
+}
+
+// Home returns the armada home directory
+func Home() string {
+	if home == "" {
+		dir, err := DefaultHome()
+		if err != nil {
+			dir = LegacyOutputDir
+		}
+		_ = SetHome(dir)
+	}
+	return home
+}
+
+// HomePath returns the path of elem relative to armada home directory
+func HomePath(elem ...string) string {
+	return filepath.Join(append([]string{Home()}, elem...)...)
+}
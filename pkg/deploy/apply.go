@@ -0,0 +1,151 @@
+package deploy
+
+import (
+	"encoding/json"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pkg/errors"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/mergepatch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// LastAppliedAnnotation keeps the last applied configuration of an object, the same annotation is used by kubectl apply
+const LastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// Apply results
+const (
+	Created    = "created"
+	Configured = "configured"
+	Unchanged  = "unchanged"
+)
+
+// Apply creates the object or updates it with a three way merge of the last applied, the live and the new configuration,
+// so fields removed from the manifest are removed from the live object while fields set by the cluster are kept.
+func Apply(client dynamic.Interface, mapper meta.RESTMapper, obj *unstructured.Unstructured) (string, error) {
+	resourceClient, err := ResourceInterface(client, mapper, obj)
+	if err != nil {
+		return "", err
+	}
+
+	modified, err := setLastApplied(obj)
+	if err != nil {
+		return "", err
+	}
+
+	live, err := resourceClient.Get(obj.GetName(), metav1.GetOptions{})
+	if apierr.IsNotFound(err) {
+		_, err = resourceClient.Create(obj, metav1.CreateOptions{})
+		if err != nil {
+			return "", errors.Wrapf(err, "creating %s %s", obj.GetKind(), obj.GetName())
+		}
+		return Created, nil
+	} else if err != nil {
+		return "", errors.Wrapf(err, "getting %s %s", obj.GetKind(), obj.GetName())
+	}
+
+	patchType, patch, err := threeWayPatch(obj.GroupVersionKind(), live, modified)
+	if err != nil {
+		return "", errors.Wrapf(err, "creating patch of %s %s", obj.GetKind(), obj.GetName())
+	}
+	if string(patch) == "{}" {
+		return Unchanged, nil
+	}
+
+	_, err = resourceClient.Patch(obj.GetName(), patchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return "", errors.Wrapf(err, "patching %s %s", obj.GetKind(), obj.GetName())
+	}
+	return Configured, nil
+}
+
+// setLastApplied stores the object configuration in the last applied annotation and returns the annotated object
+func setLastApplied(obj *unstructured.Unstructured) ([]byte, error) {
+	original := obj.DeepCopy()
+	annotations := original.GetAnnotations()
+	delete(annotations, LastAppliedAnnotation)
+	original.SetAnnotations(annotations)
+
+	lastApplied, err := json.Marshal(original.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	annotations = obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[LastAppliedAnnotation] = string(lastApplied)
+	obj.SetAnnotations(annotations)
+	return json.Marshal(obj.Object)
+}
+
+// threeWayPatch returns the patch from the live object to the modified configuration. Built in kinds get a strategic
+// merge patch, other kinds a json merge patch.
+func threeWayPatch(gvk schema.GroupVersionKind, live *unstructured.Unstructured, modified []byte) (types.PatchType, []byte, error) {
+	current, err := json.Marshal(live.Object)
+	if err != nil {
+		return "", nil, err
+	}
+	original := []byte(live.GetAnnotations()[LastAppliedAnnotation])
+
+	versioned, err := scheme.Scheme.New(gvk)
+	if runtime.IsNotRegisteredError(err) {
+		preconditions := []mergepatch.PreconditionFunc{
+			mergepatch.RequireKeyUnchanged("apiVersion"),
+			mergepatch.RequireKeyUnchanged("kind"),
+			mergepatch.RequireMetadataKeyUnchanged("name"),
+		}
+		patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch(original, modified, current, preconditions...)
+		return types.MergePatchType, patch, err
+	} else if err != nil {
+		return "", nil, err
+	}
+
+	lookupPatchMeta, err := strategicpatch.NewPatchMetaFromStruct(versioned)
+	if err != nil {
+		return "", nil, err
+	}
+	patch, err := strategicpatch.CreateThreeWayMergePatch(original, modified, current, lookupPatchMeta, true)
+	return types.StrategicMergePatchType, patch, err
+}
+
+// mergePatch applies the patch to the live object locally
+func mergePatch(gvk schema.GroupVersionKind, live *unstructured.Unstructured, patchType types.PatchType, patch []byte) (*unstructured.Unstructured, error) {
+	current, err := json.Marshal(live.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	var merged []byte
+	if patchType == types.StrategicMergePatchType {
+		versioned, err := scheme.Scheme.New(gvk)
+		if err != nil {
+			return nil, err
+		}
+		merged, err = strategicpatch.StrategicMergePatch(current, patch, versioned)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		merged, err = jsonpatch.MergePatch(current, patch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &unstructured.Unstructured{}
+	if err := json.Unmarshal(merged, &result.Object); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,129 @@
+package deploy_test
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+
+	"github.com/dimaunx/armada/pkg/deploy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const configMapManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: demo
+  namespace: kube-system
+data:
+  mode: %s
+  %s: "true"
+`
+
+const ipPoolManifest = `apiVersion: crd.projectcalico.org/v1
+kind: IPPool
+metadata:
+  name: default-ipv4-ippool
+spec:
+  cidr: 10.0.0.0/16
+  ipipMode: %s
+`
+
+// decodeOne decodes a single object manifest
+func decodeOne(manifest string) *unstructured.Unstructured {
+	objects, err := deploy.Decode(manifest)
+	Ω(err).ShouldNot(HaveOccurred())
+	Expect(objects).Should(HaveLen(1))
+	return objects[0]
+}
+
+var _ = Describe("Apply tests", func() {
+
+	mapper := newTestMapper()
+	ipPools := schema.GroupVersionResource{Group: "crd.projectcalico.org", Version: "v1", Resource: "ippools"}
+
+	Context("Apply", func() {
+		It("Should create, update and leave unchanged a built in kind", func() {
+			client := newTestClient()
+
+			result, err := deploy.Apply(client, mapper, decodeOne(strings.NewReplacer("%s", "one").Replace(configMapManifest)))
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(result).Should(Equal(deploy.Created))
+
+			result, err = deploy.Apply(client, mapper, decodeOne(strings.NewReplacer("%s", "one").Replace(configMapManifest)))
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(result).Should(Equal(deploy.Unchanged))
+
+			result, err = deploy.Apply(client, mapper, decodeOne(strings.NewReplacer("%s", "two").Replace(configMapManifest)))
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(result).Should(Equal(deploy.Configured))
+
+			configMap := &corev1.ConfigMap{}
+			err = getObject(client, configMaps, "kube-system", "demo", configMap)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(configMap.Data).Should(Equal(map[string]string{"mode": "two", "two": "true"}))
+			Expect(configMap.Annotations).Should(HaveKey(deploy.LastAppliedAnnotation))
+		})
+		It("Should update a custom resource with a merge patch", func() {
+			client := newTestClient()
+
+			result, err := deploy.Apply(client, mapper, decodeOne(strings.Replace(ipPoolManifest, "%s", "Always", 1)))
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(result).Should(Equal(deploy.Created))
+
+			result, err = deploy.Apply(client, mapper, decodeOne(strings.Replace(ipPoolManifest, "%s", "Never", 1)))
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(result).Should(Equal(deploy.Configured))
+
+			ipPool, err := client.Resource(ipPools).Get("default-ipv4-ippool", metav1.GetOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+			mode, _, err := unstructured.NestedString(ipPool.Object, "spec", "ipipMode")
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(mode).Should(Equal("Never"))
+		})
+	})
+	Context("Diff", func() {
+		It("Should return the objects that differ from the manifest", func() {
+			client := newTestClient()
+
+			manifest := strings.NewReplacer("%s", "one").Replace(configMapManifest)
+			diffs, err := deploy.Diff(client, mapper, manifest)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(diffs).Should(HaveLen(1))
+			Expect(diffs[0].Name).Should(Equal("ConfigMap.kube-system.demo"))
+			Expect(diffs[0].Live).Should(BeEmpty())
+
+			err = deploy.Resources("cl1", client, mapper, manifest, "Demo")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			diffs, err = deploy.Diff(client, mapper, manifest)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(diffs).Should(BeEmpty())
+
+			diffs, err = deploy.Diff(client, mapper, strings.NewReplacer("%s", "two").Replace(configMapManifest))
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(diffs).Should(HaveLen(1))
+			Expect(diffs[0].Live).Should(ContainSubstring("mode: one"))
+			Expect(diffs[0].Live).ShouldNot(ContainSubstring(deploy.LastAppliedAnnotation))
+			Expect(diffs[0].Merged).Should(ContainSubstring("mode: two"))
+			Expect(diffs[0].Merged).ShouldNot(ContainSubstring("one:"))
+		})
+		It("Should write the diffs with the diff program", func() {
+			if _, err := exec.LookPath("diff"); err != nil {
+				Skip("diff is not installed")
+			}
+
+			diffs := []deploy.ObjectDiff{{Name: "ConfigMap.kube-system.demo", Live: "mode: one\n", Merged: "mode: two\n"}}
+			var out bytes.Buffer
+			err := deploy.WriteDiff(&out, "cl1", diffs, "diff -u -N")
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(out.String()).Should(ContainSubstring("LIVE-cl1/ConfigMap.kube-system.demo"))
+			Expect(out.String()).Should(ContainSubstring("-mode: one"))
+			Expect(out.String()).Should(ContainSubstring("+mode: two"))
+		})
+	})
+})
@@ -0,0 +1,16 @@
+package deploy
+
+import (
+	"time"
+
+	"github.com/dimaunx/armada/pkg/wait"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// WaitForCrdEstablished waits until the api server established the CRD and serves its custom resources.
+// Fails right away if the CRD names conflict with another CRD.
+func WaitForCrdEstablished(clName string, client dynamic.Interface, mapper meta.RESTMapper, crd *unstructured.Unstructured, timeout time.Duration) error {
+	return wait.ForReady(clName, nil, client, mapper, crd, timeout)
+}
@@ -0,0 +1,114 @@
+package deploy
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+)
+
+// DeleteOptions are the options of DeleteResources
+type DeleteOptions struct {
+	// Timeout is how long to wait for each object to go away
+	Timeout time.Duration
+
+	// RemoveFinalizers if to remove the finalizers of objects still terminating after the timeout
+	RemoveFinalizers bool
+}
+
+// DeleteResources deletes the k8s resources of the manifest in reverse dependency order and waits for each of them to go away
+func DeleteResources(clName string, client dynamic.Interface, mapper meta.RESTMapper, deploymentFile string, resourceName string, opts DeleteOptions) error {
+	objects, err := Decode(deploymentFile)
+	if err != nil {
+		return errors.Wrapf(err, "%s resources", resourceName)
+	}
+
+	err = DeleteObjects(clName, client, mapper, objects, opts)
+	if err != nil {
+		return err
+	}
+	log.Debugf("✔ %s resources were removed from %s.", resourceName, clName)
+	return nil
+}
+
+// DeleteObjects deletes the objects in reverse dependency order and waits for each of them to go away
+func DeleteObjects(clName string, client dynamic.Interface, mapper meta.RESTMapper, objects []*unstructured.Unstructured, opts DeleteOptions) error {
+	SortForDelete(objects)
+	for _, obj := range objects {
+		err := Delete(client, mapper, obj, opts)
+		if err != nil {
+			return err
+		}
+		log.Debugf("✔ %s %s was deleted from %s.", obj.GetKind(), obj.GetName(), clName)
+	}
+	return nil
+}
+
+// Delete deletes the object with foreground propagation, so the dependents are deleted first, and waits for it to go away.
+// Objects that do not exist and kinds the cluster does not serve anymore are skipped.
+func Delete(client dynamic.Interface, mapper meta.RESTMapper, obj *unstructured.Unstructured, opts DeleteOptions) error {
+	gvk := obj.GroupVersionKind()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		log.Debugf("%s is not served, skipping %s.", gvk, obj.GetName())
+		return nil
+	} else if err != nil {
+		return err
+	}
+	resourceClient := scopedResourceInterface(client, mapping, obj)
+
+	propagation := metav1.DeletePropagationForeground
+	err = resourceClient.Delete(obj.GetName(), &metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if apierr.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrapf(err, "deleting %s %s", obj.GetKind(), obj.GetName())
+	}
+
+	err = waitForDeletion(resourceClient, obj.GetName(), opts.Timeout)
+	if err != wait.ErrWaitTimeout {
+		return err
+	}
+
+	live, err := resourceClient.Get(obj.GetName(), metav1.GetOptions{})
+	if apierr.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	finalizers := live.GetFinalizers()
+	if len(finalizers) == 0 {
+		return errors.Errorf("timed out waiting for %s %s to be deleted", obj.GetKind(), obj.GetName())
+	}
+	if !opts.RemoveFinalizers {
+		return errors.Errorf("timed out waiting for %s %s to be deleted, it is blocked by finalizers: %s",
+			obj.GetKind(), obj.GetName(), strings.Join(finalizers, ", "))
+	}
+
+	log.Warnf("Removing finalizers %s of %s %s.", strings.Join(finalizers, ", "), obj.GetKind(), obj.GetName())
+	_, err = resourceClient.Patch(obj.GetName(), types.MergePatchType, []byte(`{"metadata":{"finalizers":null}}`), metav1.PatchOptions{})
+	if err != nil && !apierr.IsNotFound(err) {
+		return errors.Wrapf(err, "removing finalizers of %s %s", obj.GetKind(), obj.GetName())
+	}
+	return waitForDeletion(resourceClient, obj.GetName(), opts.Timeout)
+}
+
+// waitForDeletion waits until the object is not found
+func waitForDeletion(resourceClient dynamic.ResourceInterface, name string, timeout time.Duration) error {
+	return wait.PollImmediate(time.Second, timeout, func() (bool, error) {
+		_, err := resourceClient.Get(name, metav1.GetOptions{})
+		if apierr.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+}
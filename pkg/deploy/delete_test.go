@@ -0,0 +1,110 @@
+package deploy_test
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dimaunx/armada/pkg/deploy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const undeployManifest = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: demo
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: demo
+  namespace: demo
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: demo
+  namespace: demo
+---
+apiVersion: crd.projectcalico.org/v1
+kind: IPPool
+metadata:
+  name: default-ipv4-ippool
+  finalizers:
+  - example.com/cleanup
+spec:
+  cidr: 10.0.0.0/16
+`
+
+var _ = Describe("Delete tests", func() {
+
+	mapper := newTestMapper()
+	ipPools := schema.GroupVersionResource{Group: "crd.projectcalico.org", Version: "v1", Resource: "ippools"}
+
+	Context("Order", func() {
+		It("Should sort the objects in reverse dependency order", func() {
+			objects, err := deploy.Decode(undeployManifest)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			deploy.SortForDelete(objects)
+
+			var kinds []string
+			for _, obj := range objects {
+				kinds = append(kinds, obj.GetKind())
+			}
+			Expect(kinds).Should(Equal([]string{"IPPool", "DaemonSet", "ServiceAccount", "Namespace"}))
+		})
+	})
+	Context("Delete", func() {
+		It("Should delete the resources and skip the missing ones", func() {
+			client := newTestClient()
+
+			manifest := strings.Replace(undeployManifest, "  finalizers:\n  - example.com/cleanup\n", "", 1)
+			err := deploy.Resources("cl1", client, mapper, manifest, "Demo")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			opts := deploy.DeleteOptions{Timeout: 5 * time.Second}
+			err = deploy.DeleteResources("cl1", client, mapper, manifest, "Demo", opts)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			_, err = client.Resource(appsv1.SchemeGroupVersion.WithResource("daemonsets")).Namespace("demo").Get("demo", metav1.GetOptions{})
+			Expect(err).Should(HaveOccurred())
+			_, err = client.Resource(corev1.SchemeGroupVersion.WithResource("namespaces")).Get("demo", metav1.GetOptions{})
+			Expect(err).Should(HaveOccurred())
+
+			err = deploy.DeleteResources("cl1", client, mapper, manifest, "Demo", opts)
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+		It("Should report the finalizers blocking the deletion", func() {
+			client := newTestClient()
+
+			err := deploy.Resources("cl1", client, mapper, undeployManifest, "Demo")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			err = deploy.DeleteResources("cl1", client, mapper, undeployManifest, "Demo", deploy.DeleteOptions{Timeout: time.Second})
+			Ω(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("example.com/cleanup"))
+
+			ipPool, err := client.Resource(ipPools).Get("default-ipv4-ippool", metav1.GetOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(ipPool.GetDeletionTimestamp()).ShouldNot(BeNil())
+		})
+		It("Should remove the finalizers after the timeout", func() {
+			client := newTestClient()
+
+			err := deploy.Resources("cl1", client, mapper, undeployManifest, "Demo")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			opts := deploy.DeleteOptions{Timeout: time.Second, RemoveFinalizers: true}
+			err = deploy.DeleteResources("cl1", client, mapper, undeployManifest, "Demo", opts)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			_, err = client.Resource(ipPools).Get("default-ipv4-ippool", metav1.GetOptions{})
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+})
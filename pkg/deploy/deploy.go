@@ -1,175 +1,199 @@
 package deploy
 
 import (
-	"regexp"
+	"io"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
-	appsv1 "k8s.io/api/apps/v1"
-	corev1 "k8s.io/api/core/v1"
-	extv1beta "k8s.io/api/extensions/v1beta1"
-	policyv1beta1 "k8s.io/api/policy/v1beta1"
-	rbacv1 "k8s.io/api/rbac/v1"
-	apiextv1beta "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
-	apiextclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
-	apiextscheme "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/scheme"
 	apierr "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
 )
 
-// Resources deploys k8s resources
-func Resources(clName string, clientSet kubernetes.Interface, deploymentFile string, resourceName string) error {
-	acceptedK8sTypes := regexp.MustCompile(`(Role|RoleBinding|ClusterRole|ClusterRoleBinding|ServiceAccount|ConfigMap|DaemonSet|Deployment|Service|Pod)`)
-	fileAsString := deploymentFile[:]
-	sepYamlfiles := strings.Split(fileAsString, "---")
-	for _, f := range sepYamlfiles {
-		if f == "\n" || f == "" {
-			// ignore empty cases
+// discoveryTimeout is how long to wait for the api server to serve a kind, eg: a custom resource of a new CRD
+const discoveryTimeout = 30 * time.Second
+
+// crdEstablishTimeout is how long to wait for a new CRD to be established before its custom resources are applied
+const crdEstablishTimeout = time.Minute
+
+// resettableMapper is a rest mapper with a discovery cache that can be invalidated, eg: restmapper.DeferredDiscoveryRESTMapper
+type resettableMapper interface {
+	Reset()
+}
+
+// Decode decodes a multi document yaml or json manifest as a stream into unstructured objects, lists are flattened
+func Decode(manifest string) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errors.Wrap(err, "decoding manifest")
+		}
+
+		if len(obj.Object) == 0 {
+			// ignore empty documents
 			continue
 		}
 
-		decode := scheme.Codecs.UniversalDeserializer().Decode
-		obj, groupVersionKind, err := decode([]byte(f), nil, nil)
+		if obj.GetKind() == "" || obj.GetAPIVersion() == "" {
+			return nil, errors.Errorf("object %q has no kind or apiVersion", obj.GetName())
+		}
 
-		if err != nil {
-			return errors.Wrap(err, "Error while decoding YAML object. Err was: ")
+		if obj.IsList() {
+			err := obj.EachListItem(func(item runtime.Object) error {
+				objects = append(objects, item.(*unstructured.Unstructured))
+				return nil
+			})
+			if err != nil {
+				return nil, errors.Wrapf(err, "decoding %s", obj.GetKind())
+			}
+			continue
 		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
 
-		if !acceptedK8sTypes.MatchString(groupVersionKind.Kind) {
-			log.Warnf("The file contains K8s object types which are not supported! Skipping object with type: %s", groupVersionKind.Kind)
-		} else {
-			switch o := obj.(type) {
-			case *corev1.ServiceAccount:
-				result, err := clientSet.CoreV1().ServiceAccounts(o.Namespace).Create(o)
-				if err != nil && !apierr.IsAlreadyExists(err) {
-					return err
-				} else if err == nil {
-					log.Debugf("✔ ServiceAccount %s was created for %s at: %s", o.Name, clName, result.CreationTimestamp)
-				}
-			case *rbacv1.Role:
-				result, err := clientSet.RbacV1().Roles(o.Namespace).Create(o)
-				if err != nil && !apierr.IsAlreadyExists(err) {
-					return err
-				} else if err == nil {
-					log.Debugf("✔ Role %s created for %s at: %s", o.Name, clName, result.CreationTimestamp)
-				}
-			case *rbacv1.RoleBinding:
-				result, err := clientSet.RbacV1().RoleBindings(o.Namespace).Create(o)
-				if err != nil && !apierr.IsAlreadyExists(err) {
-					return err
-				} else if err == nil {
-					log.Debugf("✔ RoleBinding %s created for %s at: %s", o.Name, clName, result.CreationTimestamp)
-				}
-			case *rbacv1.ClusterRole:
-				result, err := clientSet.RbacV1().ClusterRoles().Create(o)
-				if err != nil && !apierr.IsAlreadyExists(err) {
-					return err
-				} else if err == nil {
-					log.Debugf("✔ ClusterRole %s was created for %s at: %s", o.Name, clName, result.CreationTimestamp)
-				}
-			case *rbacv1.ClusterRoleBinding:
-				result, err := clientSet.RbacV1().ClusterRoleBindings().Create(o)
-				if err != nil && !apierr.IsAlreadyExists(err) {
-					return err
-				} else if err == nil {
-					log.Debugf("✔ ClusterRoleBinding %s was created for %s at: %s", o.Name, clName, result.CreationTimestamp)
-				}
-			case *corev1.ConfigMap:
-				result, err := clientSet.CoreV1().ConfigMaps(o.Namespace).Create(o)
-				if err != nil && !apierr.IsAlreadyExists(err) {
-					return err
-				} else if err == nil {
-					log.Debugf("✔ ConfigMap %s was created for %s at: %s", o.Name, clName, result.CreationTimestamp)
-				}
-			case *corev1.Service:
-				result, err := clientSet.CoreV1().Services(o.Namespace).Create(o)
-				if err != nil && !apierr.IsAlreadyExists(err) {
-					return err
-				} else if err == nil {
-					log.Debugf("✔ Service %s was created for %s at: %s", o.Name, clName, result.CreationTimestamp)
-				}
-			case *corev1.Pod:
-				result, err := clientSet.CoreV1().Pods(o.Namespace).Create(o)
-				if err != nil && !apierr.IsAlreadyExists(err) {
-					return err
-				} else if err == nil {
-					log.Debugf("✔ Pod %s was created for %s at: %s", o.Name, clName, result.CreationTimestamp)
-				}
-			case *policyv1beta1.PodSecurityPolicy:
-				result, err := clientSet.PolicyV1beta1().PodSecurityPolicies().Create(o)
-				if err != nil && !apierr.IsAlreadyExists(err) {
-					return err
-				} else if err == nil {
-					log.Debugf("✔ PodSecurityPolicy %s was created for %s at: %s", o.Name, clName, result.CreationTimestamp)
-				}
-			case *appsv1.DaemonSet:
-				result, err := clientSet.AppsV1().DaemonSets(o.Namespace).Create(o)
-				if err != nil && !apierr.IsAlreadyExists(err) {
-					return err
-				} else if err == nil {
-					log.Debugf("✔ Daemonset %s was created for %s at: %s", o.Name, clName, result.CreationTimestamp)
-				}
-			case *extv1beta.DaemonSet:
-				result, err := clientSet.ExtensionsV1beta1().DaemonSets(o.Namespace).Create(o)
-				if err != nil && !apierr.IsAlreadyExists(err) {
-					return err
-				} else if err == nil {
-					log.Debugf("✔ Daemonset %s was created for %s at: %s.", o.Name, clName, result.CreationTimestamp)
-				}
-			case *appsv1.Deployment:
-				result, err := clientSet.AppsV1().Deployments(o.Namespace).Create(o)
-				if err != nil && !apierr.IsAlreadyExists(err) {
-					return err
-				} else if err == nil {
-					log.Debugf("✔ Deployment %s was created for %s at: %s", o.Name, clName, result.CreationTimestamp)
-				}
-			case *extv1beta.Deployment:
-				result, err := clientSet.ExtensionsV1beta1().Deployments(o.Namespace).Create(o)
-				if err != nil && !apierr.IsAlreadyExists(err) {
-					return err
-				} else if err == nil {
-					log.Debugf("✔ Deployment %s created for %s at: %s", o.Name, clName, result.CreationTimestamp)
-				}
+// RESTMapping returns the rest mapping of the object kind. If the kind is unknown the mapper is reset and
+// the lookup is retried until the api server serves it, eg: a custom resource applied right after its CRD.
+func RESTMapping(mapper meta.RESTMapper, gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err == nil || !meta.IsNoMatchError(err) {
+		return mapping, err
+	}
+
+	resettable, ok := mapper.(resettableMapper)
+	if !ok {
+		return nil, err
+	}
+
+	pollErr := wait.PollImmediate(time.Second, discoveryTimeout, func() (bool, error) {
+		resettable.Reset()
+		mapping, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				return false, nil
 			}
+			return false, err
 		}
+		return true, nil
+	})
+	if pollErr != nil {
+		return nil, errors.Wrapf(err, "resolving %s", gvk)
 	}
-	log.Debugf("✔ %s resources were deployed to %s.", resourceName, clName)
-	return nil
+	return mapping, nil
 }
 
-// CrdResources deploys k8s CRD resources
-func CrdResources(clName string, apiExtClientSet apiextclientset.Interface, deploymentFile string) error {
-	acceptedK8sTypes := regexp.MustCompile(`(CustomResourceDefinition)`)
-	fileAsString := deploymentFile[:]
-	sepYamlfiles := strings.Split(fileAsString, "---")
-	for _, f := range sepYamlfiles {
-		if f == "\n" || f == "" {
-			// ignore empty cases
-			continue
+// ResourceInterface returns the dynamic resource client of the object in its scope. Namespaced objects without
+// a namespace are set to the default namespace.
+func ResourceInterface(client dynamic.Interface, mapper meta.RESTMapper, obj *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	mapping, err := RESTMapping(mapper, obj.GroupVersionKind())
+	if err != nil {
+		return nil, err
+	}
+	return scopedResourceInterface(client, mapping, obj), nil
+}
+
+// scopedResourceInterface returns the dynamic resource client of the mapping in the object scope
+func scopedResourceInterface(client dynamic.Interface, mapping *meta.RESTMapping, obj *unstructured.Unstructured) dynamic.ResourceInterface {
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(metav1.NamespaceDefault)
 		}
+		return client.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+	}
+	obj.SetNamespace("")
+	return client.Resource(mapping.Resource)
+}
+
+// Resources applies k8s resources of any kind served by the cluster in dependency order, existing resources are updated to match the manifest
+func Resources(clName string, client dynamic.Interface, mapper meta.RESTMapper, deploymentFile string, resourceName string) error {
+	objects, err := Decode(deploymentFile)
+	if err != nil {
+		return errors.Wrapf(err, "%s resources", resourceName)
+	}
 
-		decode := apiextscheme.Codecs.UniversalDeserializer().Decode
-		obj, groupVersionKind, err := decode([]byte(f), nil, nil)
+	err = ApplyObjects(clName, client, mapper, objects)
+	if err != nil {
+		return err
+	}
+	log.Debugf("✔ %s resources were deployed to %s.", resourceName, clName)
+	return nil
+}
 
+// ApplyObjects applies the objects in dependency order: namespaces, CRDs, RBAC, config maps and secrets, workloads and then
+// custom resources. Missing namespaces are created and each CRD is waited for until it is established.
+func ApplyObjects(clName string, client dynamic.Interface, mapper meta.RESTMapper, objects []*unstructured.Unstructured) error {
+	SortForApply(objects)
+	namespaces := map[string]bool{}
+	for _, obj := range objects {
+		mapping, err := RESTMapping(mapper, obj.GroupVersionKind())
 		if err != nil {
-			return errors.Wrap(err, "Error while decoding YAML object. Err was: ")
+			return err
 		}
 
-		if !acceptedK8sTypes.MatchString(groupVersionKind.Kind) {
-			log.Warnf("The file contains K8s object types which are not supported! Skipping object with type: %s", groupVersionKind.Kind)
-		} else {
-			switch o := obj.(type) {
-			case *apiextv1beta.CustomResourceDefinition:
-				_, err := apiExtClientSet.ApiextensionsV1beta1().CustomResourceDefinitions().Create(o)
-				if err != nil && !apierr.IsAlreadyExists(err) {
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			namespace := obj.GetNamespace()
+			if namespace == "" {
+				namespace = metav1.NamespaceDefault
+			}
+			if !namespaces[namespace] {
+				err = EnsureNamespace(clName, client, mapper, namespace)
+				if err != nil {
 					return err
-				} else if err == nil {
-					log.Debugf("✔ CRD %s was created for %s.", o.Name, clName)
 				}
+				namespaces[namespace] = true
+			}
+		}
+
+		result, err := Apply(client, mapper, obj)
+		if err != nil {
+			return err
+		}
+		log.Debugf("✔ %s %s was %s for %s.", obj.GetKind(), obj.GetName(), result, clName)
+
+		switch obj.GetKind() {
+		case "Namespace":
+			namespaces[obj.GetName()] = true
+		case "CustomResourceDefinition":
+			err = WaitForCrdEstablished(clName, client, mapper, obj, crdEstablishTimeout)
+			if err != nil {
+				return err
 			}
 		}
 	}
 	return nil
 }
+
+// EnsureNamespace creates the namespace if it does not exist
+func EnsureNamespace(clName string, client dynamic.Interface, mapper meta.RESTMapper, namespace string) error {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("Namespace")
+	obj.SetName(namespace)
+
+	resourceClient, err := ResourceInterface(client, mapper, obj)
+	if err != nil {
+		return err
+	}
+
+	_, err = resourceClient.Create(obj, metav1.CreateOptions{})
+	if err != nil && !apierr.IsAlreadyExists(err) {
+		return errors.Wrapf(err, "creating namespace %s", namespace)
+	} else if err == nil {
+		log.Debugf("✔ Namespace %s was created for %s.", namespace, clName)
+	}
+	return nil
+}
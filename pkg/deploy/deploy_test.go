@@ -1,17 +1,169 @@
 package deploy_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/dimaunx/armada/pkg/cluster"
 	"github.com/dimaunx/armada/pkg/deploy"
+	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/gobuffalo/packr/v2"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextscheme "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/scheme"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/watch"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// clusterScopedKinds are the kinds the test rest mapper maps as cluster scoped
+var clusterScopedKinds = map[string]bool{
+	"Namespace":                true,
+	"Node":                     true,
+	"PersistentVolume":         true,
+	"ClusterRole":              true,
+	"ClusterRoleBinding":       true,
+	"PodSecurityPolicy":        true,
+	"CustomResourceDefinition": true,
+	"IPPool":                   true,
+}
+
+// newTestMapper returns a rest mapper of the built in and apiextensions kinds and the calico ip pool
+func newTestMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	add := func(gvk schema.GroupVersionKind) {
+		if strings.HasSuffix(gvk.Kind, "List") || strings.HasSuffix(gvk.Kind, "Options") || strings.HasSuffix(gvk.Kind, "Event") {
+			return
+		}
+		if clusterScopedKinds[gvk.Kind] {
+			mapper.Add(gvk, meta.RESTScopeRoot)
+		} else {
+			mapper.Add(gvk, meta.RESTScopeNamespace)
+		}
+	}
+	for gvk := range scheme.Scheme.AllKnownTypes() {
+		add(gvk)
+	}
+	for gvk := range apiextscheme.Scheme.AllKnownTypes() {
+		add(gvk)
+	}
+	add(schema.GroupVersionKind{Group: "crd.projectcalico.org", Version: "v1", Kind: "IPPool"})
+	return mapper
+}
+
+// newTestClient returns a fake dynamic client that behaves like the api server where the default fake reactions
+// do not: strategic merge patches use the typed struct of the object and objects with finalizers are only marked
+// for deletion until the finalizers are removed, new CRDs are established right away
+func newTestClient() *fakedynamic.FakeDynamicClient {
+	testScheme := runtime.NewScheme()
+	client := fakedynamic.NewSimpleDynamicClient(testScheme)
+	tracker := k8stesting.NewObjectTracker(testScheme, serializer.NewCodecFactory(testScheme).UniversalDecoder())
+
+	client.ReactionChain = nil
+	client.AddReactor("create", "customresourcedefinitions", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		crd := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured).DeepCopy()
+		conditions := []interface{}{
+			map[string]interface{}{"type": "NamesAccepted", "status": "True"},
+			map[string]interface{}{"type": "Established", "status": "True"},
+		}
+		if err := unstructured.SetNestedSlice(crd.Object, conditions, "status", "conditions"); err != nil {
+			return true, nil, err
+		}
+		return true, crd, tracker.Create(action.GetResource(), crd, action.GetNamespace())
+	})
+	client.AddReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patchAction := action.(k8stesting.PatchAction)
+		current, err := tracker.Get(action.GetResource(), action.GetNamespace(), patchAction.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+
+		live := current.(*unstructured.Unstructured)
+		original, err := json.Marshal(live.Object)
+		if err != nil {
+			return true, nil, err
+		}
+
+		var merged []byte
+		switch patchAction.GetPatchType() {
+		case types.StrategicMergePatchType:
+			versioned, err := scheme.Scheme.New(live.GroupVersionKind())
+			if err != nil {
+				return true, nil, err
+			}
+			merged, err = strategicpatch.StrategicMergePatch(original, patchAction.GetPatch(), versioned)
+			if err != nil {
+				return true, nil, err
+			}
+		case types.MergePatchType:
+			merged, err = jsonpatch.MergePatch(original, patchAction.GetPatch())
+			if err != nil {
+				return true, nil, err
+			}
+		default:
+			return false, nil, nil
+		}
+
+		result := &unstructured.Unstructured{}
+		if err := json.Unmarshal(merged, &result.Object); err != nil {
+			return true, nil, err
+		}
+		if result.GetDeletionTimestamp() != nil && len(result.GetFinalizers()) == 0 {
+			return true, result, tracker.Delete(action.GetResource(), action.GetNamespace(), result.GetName())
+		}
+		return true, result, tracker.Update(action.GetResource(), result, action.GetNamespace())
+	})
+	client.AddReactor("delete", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		deleteAction := action.(k8stesting.DeleteAction)
+		current, err := tracker.Get(action.GetResource(), action.GetNamespace(), deleteAction.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+
+		live := current.(*unstructured.Unstructured)
+		if len(live.GetFinalizers()) == 0 {
+			return true, nil, tracker.Delete(action.GetResource(), action.GetNamespace(), live.GetName())
+		}
+		now := metav1.Now()
+		live.SetDeletionTimestamp(&now)
+		return true, nil, tracker.Update(action.GetResource(), live, action.GetNamespace())
+	})
+	client.AddReactor("*", "*", k8stesting.ObjectReaction(tracker))
+	client.WatchReactionChain = nil
+	client.AddWatchReactor("*", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		w, err := tracker.Watch(action.GetResource(), action.GetNamespace())
+		return true, w, err
+	})
+	return client
+}
+
+// getObject gets the object from the fake dynamic client and converts it to obj
+func getObject(client *fakedynamic.FakeDynamicClient, gvr schema.GroupVersionResource, namespace, name string, obj interface{}) error {
+	result, err := client.Resource(gvr).Namespace(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(result.Object, obj)
+}
+
+var (
+	daemonSets = appsv1.SchemeGroupVersion.WithResource("daemonsets")
+	configMaps = corev1.SchemeGroupVersion.WithResource("configmaps")
+	secrets    = corev1.SchemeGroupVersion.WithResource("secrets")
 )
 
 func TestDeployment(t *testing.T) {
@@ -22,6 +174,7 @@ func TestDeployment(t *testing.T) {
 var _ = Describe("Deploy tests", func() {
 
 	box := packr.New("configs", "../../configs")
+	mapper := newTestMapper()
 
 	Context("Deployment tests", func() {
 		It("Should deploy weave resources", func() {
@@ -31,21 +184,49 @@ var _ = Describe("Deploy tests", func() {
 				PodSubnet: "1.2.3.4/8",
 			}
 
-			clientSet := testclient.NewSimpleClientset()
+			client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
 
 			deployfile, err := cluster.GenerateWeaveDeploymentFile(cl, box)
 			Ω(err).ShouldNot(HaveOccurred())
 
-			err = deploy.Resources(cl.Name, clientSet, deployfile, "Weave")
+			err = deploy.Resources(cl.Name, client, mapper, deployfile, "Weave")
 			Ω(err).ShouldNot(HaveOccurred())
 
-			result, err := clientSet.AppsV1().DaemonSets("kube-system").Get("weave-net", metav1.GetOptions{})
+			result := &appsv1.DaemonSet{}
+			err = getObject(client, daemonSets, "kube-system", "weave-net", result)
 			Ω(err).ShouldNot(HaveOccurred())
 			fmt.Printf("Name: %s, Value: %s", result.Spec.Template.Spec.Containers[0].Env[1].Name, result.Spec.Template.Spec.Containers[0].Env[1].Value)
 
 			Expect(result.Spec.Template.Spec.Containers[0].Image).Should(Equal("docker.io/weaveworks/weave-kube:2.6.0"))
 			Expect(result.Spec.Template.Spec.Containers[0].Env[1].Value).Should(Equal(cl.PodSubnet))
 		})
+		It("Should deploy weave resources with encryption secret", func() {
+
+			cl := &cluster.Config{
+				Name:      "cl1",
+				PodSubnet: "1.2.3.4/8",
+				Weave:     cluster.WeaveOptions{Password: "secret"},
+			}
+
+			client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+
+			deployfile, err := cluster.GenerateWeaveDeploymentFile(cl, box)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			err = deploy.Resources(cl.Name, client, mapper, deployfile, "Weave")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			secret := &corev1.Secret{}
+			err = getObject(client, secrets, "kube-system", "weave-passwd", secret)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(secret.StringData["weave-passwd"]).Should(Equal("secret"))
+
+			result := &appsv1.DaemonSet{}
+			err = getObject(client, daemonSets, "kube-system", "weave-net", result)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(result.Spec.Template.Spec.Containers[0].Env[2].Name).Should(Equal("WEAVE_PASSWORD"))
+			Expect(result.Spec.Template.Spec.Containers[0].Env[2].ValueFrom.SecretKeyRef.Name).Should(Equal(secret.Name))
+		})
 		It("Should deploy flannel resources", func() {
 
 			cl := &cluster.Config{
@@ -53,15 +234,16 @@ var _ = Describe("Deploy tests", func() {
 				PodSubnet: "1.2.3.4/16",
 			}
 
-			clientSet := testclient.NewSimpleClientset()
+			client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
 
 			deployfile, err := cluster.GenerateFlannelDeploymentFile(cl, box)
 			Ω(err).ShouldNot(HaveOccurred())
 
-			err = deploy.Resources(cl.Name, clientSet, deployfile, "Flannel")
+			err = deploy.Resources(cl.Name, client, mapper, deployfile, "Flannel")
 			Ω(err).ShouldNot(HaveOccurred())
 
-			result, err := clientSet.CoreV1().ConfigMaps("kube-system").Get("kube-flannel-cfg", metav1.GetOptions{})
+			result := &corev1.ConfigMap{}
+			err = getObject(client, configMaps, "kube-system", "kube-flannel-cfg", result)
 			Ω(err).ShouldNot(HaveOccurred())
 			fmt.Print(result.Data["net-conf.json"])
 			contains := strings.Contains(result.Data["net-conf.json"], cl.PodSubnet)
@@ -74,20 +256,205 @@ var _ = Describe("Deploy tests", func() {
 				PodSubnet: "1.2.3.4/4",
 			}
 
-			clientSet := testclient.NewSimpleClientset()
+			client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
 
 			deployfile, err := cluster.GenerateCalicoDeploymentFile(cl, box)
 			Ω(err).ShouldNot(HaveOccurred())
 
-			err = deploy.Resources(cl.Name, clientSet, deployfile, "Calico")
+			err = deploy.Resources(cl.Name, client, mapper, deployfile, "Calico")
 			Ω(err).ShouldNot(HaveOccurred())
 
-			result, err := clientSet.AppsV1().DaemonSets("kube-system").Get("calico-node", metav1.GetOptions{})
+			result := &appsv1.DaemonSet{}
+			err = getObject(client, daemonSets, "kube-system", "calico-node", result)
 			Ω(err).ShouldNot(HaveOccurred())
 			fmt.Printf("Name: %s, Value: %s", result.Spec.Template.Spec.Containers[0].Env[8].Name, result.Spec.Template.Spec.Containers[0].Env[8].Value)
 
 			Expect(result.Spec.Template.Spec.Containers[0].Image).Should(Equal("calico/node:v3.9.3"))
 			Expect(result.Spec.Template.Spec.Containers[0].Env[8].Value).Should(Equal(cl.PodSubnet))
 		})
+		It("Should deploy calico custom resource definitions", func() {
+
+			cl := &cluster.Config{
+				Name: "cl1",
+			}
+
+			client := newTestClient()
+
+			crdFile, err := cluster.GenerateCalicoCrdFile(cl, box)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			err = deploy.Resources(cl.Name, client, mapper, crdFile, "Calico")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			crds := schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1beta1", Resource: "customresourcedefinitions"}
+			result, err := client.Resource(crds).Get("ippools.crd.projectcalico.org", metav1.GetOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(result.GetNamespace()).Should(BeEmpty())
+		})
+	})
+	Context("Dependency order tests", func() {
+		manifest := `apiVersion: crd.projectcalico.org/v1
+kind: IPPool
+metadata:
+  name: default-ipv4-ippool
+spec:
+  cidr: 10.0.0.0/16
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: demo
+  namespace: demo
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: demo
+  namespace: other
+---
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: ippools.crd.projectcalico.org
+spec:
+  group: crd.projectcalico.org
+  version: v1
+  scope: Cluster
+  names:
+    kind: IPPool
+    plural: ippools
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: demo
+`
+		It("Should sort objects in dependency order", func() {
+			objects, err := deploy.Decode(manifest)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			deploy.SortForApply(objects)
+			var kinds []string
+			for _, obj := range objects {
+				kinds = append(kinds, obj.GetKind())
+			}
+			Expect(kinds).Should(Equal([]string{"Namespace", "CustomResourceDefinition", "ConfigMap", "Deployment", "IPPool"}))
+		})
+		It("Should create missing namespaces and custom resources after their CRD is established", func() {
+			client := newTestClient()
+
+			err := deploy.Resources("cl1", client, mapper, manifest, "Demo")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			var created []string
+			for _, action := range client.Actions() {
+				if createAction, ok := action.(k8stesting.CreateAction); ok {
+					obj := createAction.GetObject().(*unstructured.Unstructured)
+					created = append(created, obj.GetKind()+"/"+obj.GetName())
+				}
+			}
+			Expect(created).Should(Equal([]string{
+				"Namespace/demo",
+				"CustomResourceDefinition/ippools.crd.projectcalico.org",
+				"Namespace/other",
+				"ConfigMap/demo",
+				"Deployment/demo",
+				"IPPool/default-ipv4-ippool",
+			}))
+		})
+		It("Should fail if the CRD is not established in time", func() {
+			client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+
+			objects, err := deploy.Decode(manifest)
+			Ω(err).ShouldNot(HaveOccurred())
+			crd := objects[3]
+			_, err = deploy.Apply(client, mapper, crd)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			err = deploy.WaitForCrdEstablished("cl1", client, mapper, crd, time.Second)
+			Ω(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("timed out"))
+		})
+	})
+	Context("Decode tests", func() {
+		manifest := `apiVersion: v1
+kind: Namespace
+metadata:
+  name: demo
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: demo-script
+  namespace: demo
+data:
+  script.sh: |
+    echo "---"
+    ---
+---
+
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: apps/v1
+  kind: StatefulSet
+  metadata:
+    name: demo
+- apiVersion: crd.projectcalico.org/v1
+  kind: IPPool
+  metadata:
+    name: default-ipv4-ippool
+  spec:
+    cidr: 10.0.0.0/16
+`
+		It("Should decode a multi document manifest as a stream", func() {
+			objects, err := deploy.Decode(manifest)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			var kinds []string
+			for _, obj := range objects {
+				kinds = append(kinds, obj.GetKind())
+			}
+			Expect(kinds).Should(Equal([]string{"Namespace", "ConfigMap", "StatefulSet", "IPPool"}))
+
+			script, _, err := unstructured.NestedString(objects[1].Object, "data", "script.sh")
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(script).Should(Equal("echo \"---\"\n---\n"))
+		})
+		It("Should fail to decode an object without kind", func() {
+			_, err := deploy.Decode("apiVersion: v1\nmetadata:\n  name: demo\n")
+			Ω(err).Should(HaveOccurred())
+		})
+		It("Should deploy any kind in its scope", func() {
+			client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+
+			err := deploy.Resources("cl1", client, mapper, manifest, "Demo")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			_, err = client.Resource(corev1.SchemeGroupVersion.WithResource("namespaces")).Get("demo", metav1.GetOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			configMap := &corev1.ConfigMap{}
+			err = getObject(client, configMaps, "demo", "demo-script", configMap)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			_, err = client.Resource(appsv1.SchemeGroupVersion.WithResource("statefulsets")).Namespace("default").Get("demo", metav1.GetOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			ipPools := schema.GroupVersionResource{Group: "crd.projectcalico.org", Version: "v1", Resource: "ippools"}
+			ipPool, err := client.Resource(ipPools).Get("default-ipv4-ippool", metav1.GetOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(ipPool.GetNamespace()).Should(BeEmpty())
+
+			err = deploy.Resources("cl1", client, mapper, manifest, "Demo")
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+		It("Should fail to deploy an unknown kind", func() {
+			client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme())
+
+			err := deploy.Resources("cl1", client, mapper, "apiVersion: example.com/v1\nkind: Unknown\nmetadata:\n  name: demo\n", "Demo")
+			Ω(err).Should(HaveOccurred())
+		})
 	})
 })
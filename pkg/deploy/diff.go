@@ -0,0 +1,168 @@
+package deploy
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// ObjectDiff is the live and the merged state of an object that differs from its manifest
+type ObjectDiff struct {
+	// Name identifies the object, eg: DaemonSet.kube-system.weave-net
+	Name string
+
+	// Live is the live object, empty if the object does not exist
+	Live string
+
+	// Merged is the object as it would be after applying the manifest
+	Merged string
+}
+
+// Diff returns the manifest objects whose live state differs from the state after applying the manifest
+func Diff(client dynamic.Interface, mapper meta.RESTMapper, deploymentFile string) ([]ObjectDiff, error) {
+	objects, err := Decode(deploymentFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []ObjectDiff
+	for _, obj := range objects {
+		resourceClient, err := ResourceInterface(client, mapper, obj)
+		if err != nil {
+			return nil, err
+		}
+
+		modified, err := setLastApplied(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.Join([]string{obj.GetKind(), obj.GetNamespace(), obj.GetName()}, ".")
+		name = strings.Replace(name, "..", ".", 1)
+
+		live, err := resourceClient.Get(obj.GetName(), metav1.GetOptions{})
+		if apierr.IsNotFound(err) {
+			merged, err := toComparableYaml(obj)
+			if err != nil {
+				return nil, err
+			}
+			diffs = append(diffs, ObjectDiff{Name: name, Merged: merged})
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "getting %s %s", obj.GetKind(), obj.GetName())
+		}
+
+		patchType, patch, err := threeWayPatch(obj.GroupVersionKind(), live, modified)
+		if err != nil {
+			return nil, errors.Wrapf(err, "creating patch of %s %s", obj.GetKind(), obj.GetName())
+		}
+
+		mergedObj, err := mergePatch(obj.GroupVersionKind(), live, patchType, patch)
+		if err != nil {
+			return nil, errors.Wrapf(err, "merging %s %s", obj.GetKind(), obj.GetName())
+		}
+
+		liveYaml, err := toComparableYaml(live)
+		if err != nil {
+			return nil, err
+		}
+
+		merged, err := toComparableYaml(mergedObj)
+		if err != nil {
+			return nil, err
+		}
+
+		if liveYaml != merged {
+			diffs = append(diffs, ObjectDiff{Name: name, Live: liveYaml, Merged: merged})
+		}
+	}
+	return diffs, nil
+}
+
+// toComparableYaml returns the object yaml without the status and the fields managed by the api server
+func toComparableYaml(obj *unstructured.Unstructured) (string, error) {
+	obj = obj.DeepCopy()
+	unstructured.RemoveNestedField(obj.Object, "status")
+	for _, field := range []string{"managedFields", "resourceVersion", "generation", "uid", "selfLink", "creationTimestamp"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+
+	annotations := obj.GetAnnotations()
+	delete(annotations, LastAppliedAnnotation)
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "annotations")
+	} else {
+		obj.SetAnnotations(annotations)
+	}
+
+	content, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// WriteDiff writes the live and merged objects to LIVE-<cluster> and MERGED-<cluster> directories and writes
+// the output of the diff program run on them to w, eg: diff -u -N
+func WriteDiff(w io.Writer, clName string, diffs []ObjectDiff, program string) error {
+	if len(diffs) == 0 {
+		return nil
+	}
+
+	tmpDir, err := ioutil.TempDir("", "armada-diff")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	liveDir := filepath.Join(tmpDir, "LIVE-"+clName)
+	mergedDir := filepath.Join(tmpDir, "MERGED-"+clName)
+	for _, dir := range []string{liveDir, mergedDir} {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return err
+		}
+	}
+
+	for _, diff := range diffs {
+		if diff.Live != "" {
+			if err := ioutil.WriteFile(filepath.Join(liveDir, diff.Name), []byte(diff.Live), 0644); err != nil {
+				return err
+			}
+		}
+		if err := ioutil.WriteFile(filepath.Join(mergedDir, diff.Name), []byte(diff.Merged), 0644); err != nil {
+			return err
+		}
+	}
+
+	args := strings.Fields(program)
+	if len(args) == 0 {
+		return errors.New("no diff program")
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(args[0], append(args[1:], filepath.Base(liveDir), filepath.Base(mergedDir))...)
+	cmd.Dir = tmpDir
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		// diff exits with 1 when the inputs differ
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "running %s: %s", program, stderr.String())
+	}
+	return nil
+}
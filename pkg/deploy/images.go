@@ -0,0 +1,48 @@
+package deploy
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// podSpecPaths are the paths of the pod spec in the objects running containers
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"PodTemplate":           {"template", "spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// ContainerImages returns the sorted unique images of the containers and init containers of the objects
+func ContainerImages(objects []*unstructured.Unstructured) []string {
+	seen := map[string]bool{}
+	var images []string
+	for _, obj := range objects {
+		path, ok := podSpecPaths[obj.GetKind()]
+		if !ok {
+			continue
+		}
+		for _, field := range []string{"initContainers", "containers"} {
+			containers, _, _ := unstructured.NestedSlice(obj.Object, append(append([]string{}, path...), field)...)
+			for _, c := range containers {
+				container, ok := c.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				image, _ := container["image"].(string)
+				if image != "" && !seen[image] {
+					seen[image] = true
+					images = append(images, image)
+				}
+			}
+		}
+	}
+	sort.Strings(images)
+	return images
+}
@@ -0,0 +1,52 @@
+package deploy_test
+
+import (
+	"github.com/dimaunx/armada/pkg/deploy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const imagesManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox:1.31
+      containers:
+      - name: web
+        image: nginx:alpine
+      - name: sidecar
+        image: envoyproxy/envoy:v1.12.2
+---
+apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: backup
+            image: nginx:alpine
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  image: not-an-image
+`
+
+var _ = Describe("Images tests", func() {
+	It("Should collect the unique container images of the workloads", func() {
+		objects, err := deploy.Decode(imagesManifest)
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(deploy.ContainerImages(objects)).Should(Equal([]string{"busybox:1.31", "envoyproxy/envoy:v1.12.2", "nginx:alpine"}))
+	})
+})
@@ -0,0 +1,75 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// manifestExtensions are the extensions of the manifest files read from directories
+var manifestExtensions = []string{".yaml", ".yml", ".json"}
+
+// ManifestFile is a manifest read from disk
+type ManifestFile struct {
+	// Path is the manifest file path
+	Path string
+
+	// Content is the manifest content
+	Content string
+}
+
+// ReadManifestFiles reads the manifests from files and directories. Directory files with a yaml, yml or json
+// extension are read in name order, sub directories are ignored.
+func ReadManifestFiles(paths ...string) ([]ManifestFile, error) {
+	var manifests []ManifestFile
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		files := []string{path}
+		if info.IsDir() {
+			files, err = listManifestFiles(path)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, file := range files {
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, errors.Wrapf(err, "reading manifest %s", file)
+			}
+			manifests = append(manifests, ManifestFile{Path: file, Content: string(content)})
+		}
+	}
+	return manifests, nil
+}
+
+// listManifestFiles returns the manifest files of the directory sorted by name
+func listManifestFiles(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		for _, ext := range manifestExtensions {
+			if strings.EqualFold(filepath.Ext(entry.Name()), ext) {
+				files = append(files, filepath.Join(dir, entry.Name()))
+				break
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,70 @@
+package deploy
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// kindOrder is the order kinds depend on each other, kinds not listed, eg: custom resources, come last
+var kindOrder = []string{
+	"Namespace",
+	"CustomResourceDefinition",
+	"PodSecurityPolicy",
+	"PriorityClass",
+	"StorageClass",
+	"ServiceAccount",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"ResourceQuota",
+	"LimitRange",
+	"ConfigMap",
+	"Secret",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"Service",
+	"DaemonSet",
+	"Deployment",
+	"ReplicaSet",
+	"ReplicationController",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"Pod",
+	"Ingress",
+	"NetworkPolicy",
+	"PodDisruptionBudget",
+	"HorizontalPodAutoscaler",
+	"APIService",
+	"MutatingWebhookConfiguration",
+	"ValidatingWebhookConfiguration",
+}
+
+// kindRank returns the position of the kind in kindOrder
+func kindRank(kind string) int {
+	for i, k := range kindOrder {
+		if k == kind {
+			return i
+		}
+	}
+	return len(kindOrder)
+}
+
+// SortForApply sorts the objects in dependency order, objects of the same kind keep the manifest order
+func SortForApply(objects []*unstructured.Unstructured) {
+	sort.SliceStable(objects, func(i, j int) bool {
+		return kindRank(objects[i].GetKind()) < kindRank(objects[j].GetKind())
+	})
+}
+
+// SortForDelete sorts the objects in reverse dependency order, objects of the same kind in reverse manifest order
+func SortForDelete(objects []*unstructured.Unstructured) {
+	for i, j := 0, len(objects)-1; i < j; i, j = i+1, j-1 {
+		objects[i], objects[j] = objects[j], objects[i]
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		return kindRank(objects[i].GetKind()) > kindRank(objects[j].GetKind())
+	})
+}
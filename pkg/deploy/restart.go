@@ -0,0 +1,81 @@
+package deploy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dimaunx/armada/pkg/imageref"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// restartedAtAnnotation is the pod template annotation kubectl rollout restart sets, changing it rolls the pods
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// RestartWorkloadsUsing restarts the deployments, daemon sets and stateful sets of the cluster with a container running
+// the image and returns them as kind namespace/name
+func RestartWorkloadsUsing(clName string, clientSet kubernetes.Interface, imageName string) ([]string, error) {
+	patch := []byte(fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`,
+		restartedAtAnnotation, time.Now().Format(time.RFC3339)))
+	apps := clientSet.AppsV1()
+
+	var restarted []string
+	deployments, err := apps.Deployments(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments.Items {
+		if UsesImage(&d.Spec.Template.Spec, imageName) {
+			if _, err := apps.Deployments(d.Namespace).Patch(d.Name, types.StrategicMergePatchType, patch); err != nil {
+				return restarted, err
+			}
+			restarted = append(restarted, fmt.Sprintf("Deployment %s/%s", d.Namespace, d.Name))
+		}
+	}
+
+	daemonSets, err := apps.DaemonSets(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return restarted, err
+	}
+	for _, ds := range daemonSets.Items {
+		if UsesImage(&ds.Spec.Template.Spec, imageName) {
+			if _, err := apps.DaemonSets(ds.Namespace).Patch(ds.Name, types.StrategicMergePatchType, patch); err != nil {
+				return restarted, err
+			}
+			restarted = append(restarted, fmt.Sprintf("DaemonSet %s/%s", ds.Namespace, ds.Name))
+		}
+	}
+
+	statefulSets, err := apps.StatefulSets(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return restarted, err
+	}
+	for _, sts := range statefulSets.Items {
+		if UsesImage(&sts.Spec.Template.Spec, imageName) {
+			if _, err := apps.StatefulSets(sts.Namespace).Patch(sts.Name, types.StrategicMergePatchType, patch); err != nil {
+				return restarted, err
+			}
+			restarted = append(restarted, fmt.Sprintf("StatefulSet %s/%s", sts.Namespace, sts.Name))
+		}
+	}
+
+	for _, workload := range restarted {
+		log.Infof("✔ %s was restarted in %s.", workload, clName)
+	}
+	return restarted, nil
+}
+
+// UsesImage returns if a container or an init container of the pod spec runs the image, eg: nginx matches docker.io/library/nginx:latest
+func UsesImage(spec *corev1.PodSpec, imageName string) bool {
+	want := imageref.Normalize(imageName)
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		if imageref.Normalize(container.Image) == want {
+			return true
+		}
+	}
+	return false
+}
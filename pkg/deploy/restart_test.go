@@ -0,0 +1,54 @@
+package deploy_test
+
+import (
+	"github.com/dimaunx/armada/pkg/deploy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+)
+
+// podTemplate returns a pod template with the init container and container images, empty images are omitted
+func podTemplate(initImage, image string) corev1.PodTemplateSpec {
+	template := corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "main", Image: image}}}}
+	if initImage != "" {
+		template.Spec.InitContainers = []corev1.Container{{Name: "init", Image: initImage}}
+	}
+	return template
+}
+
+var _ = Describe("Restart tests", func() {
+	It("Should match images by their normalized reference", func() {
+		spec := podTemplate("", "docker.io/library/nginx:latest").Spec
+		Expect(deploy.UsesImage(&spec, "nginx")).Should(BeTrue())
+		Expect(deploy.UsesImage(&spec, "nginx:latest")).Should(BeTrue())
+		Expect(deploy.UsesImage(&spec, "nginx:alpine")).Should(BeFalse())
+		Expect(deploy.UsesImage(&spec, "quay.io/nginx")).Should(BeFalse())
+	})
+	It("Should restart only the workloads using the image", func() {
+		clientSet := fakekubernetes.NewSimpleClientset(
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{Template: podTemplate("", "nginx:alpine")}},
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{Template: podTemplate("", "alpine")}},
+			&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "kube-system"},
+				Spec: appsv1.DaemonSetSpec{Template: podTemplate("", "busybox")}},
+			&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "demo"},
+				Spec: appsv1.StatefulSetSpec{Template: podTemplate("docker.io/library/nginx:alpine", "postgres")}},
+		)
+
+		restarted, err := deploy.RestartWorkloadsUsing("cl1", clientSet, "nginx:alpine")
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(restarted).Should(Equal([]string{"Deployment default/web", "StatefulSet demo/db"}))
+
+		web, err := clientSet.AppsV1().Deployments("default").Get("web", metav1.GetOptions{})
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(web.Spec.Template.Annotations).Should(HaveKey("kubectl.kubernetes.io/restartedAt"))
+
+		other, err := clientSet.AppsV1().Deployments("default").Get("other", metav1.GetOptions{})
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(other.Spec.Template.Annotations).ShouldNot(HaveKey("kubectl.kubernetes.io/restartedAt"))
+	})
+})
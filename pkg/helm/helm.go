@@ -0,0 +1,212 @@
+package helm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// chartMetadataFile is the chart file with the chart name and version
+const chartMetadataFile = "Chart.yaml"
+
+// InstallOptions are the options of a chart release installation
+type InstallOptions struct {
+	// ReleaseName is the release name, the chart name if empty
+	ReleaseName string
+
+	// Namespace is the release namespace
+	Namespace string
+
+	// ValuesFiles are the values files of the release
+	ValuesFiles []string
+
+	// Timeout is how long helm waits for the release hooks
+	Timeout time.Duration
+}
+
+// chartMetadata is the part of Chart.yaml armada uses
+type chartMetadata struct {
+	APIVersion string `yaml:"apiVersion"`
+	Name       string `yaml:"name"`
+	Version    string `yaml:"version"`
+}
+
+// lookPath returns the path of the helm binary, an error with the install instructions if it is not found
+func lookPath(helmPath string) (string, error) {
+	path, err := exec.LookPath(helmPath)
+	if err != nil {
+		return "", errors.Errorf("helm binary %q was not found, helm 3 is required to deploy charts, "+
+			"install it, see https://helm.sh/docs/intro/install/, or set the path of the binary", helmPath)
+	}
+	return path, nil
+}
+
+// CheckVersion returns an error if the helm binary is not found or is not helm 3
+func CheckVersion(helmPath string) error {
+	helmPath, err := lookPath(helmPath)
+	if err != nil {
+		return err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(helmPath, "version", "--short")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "running %s version: %s", helmPath, stderr.String())
+	}
+
+	version := strings.TrimSpace(stdout.String())
+	ver, err := semver.NewVersion(strings.SplitN(version, "+", 2)[0])
+	if err != nil {
+		return errors.Wrapf(err, "parsing helm version %q", version)
+	}
+	if ver.Major() != 3 {
+		return errors.Errorf("helm 3 is required, found %s", version)
+	}
+	return nil
+}
+
+// ChartName returns the name of a chart directory or .tgz archive from its Chart.yaml
+func ChartName(chart string) (string, error) {
+	info, err := os.Stat(chart)
+	if err != nil {
+		return "", err
+	}
+
+	var content []byte
+	if info.IsDir() {
+		content, err = ioutil.ReadFile(filepath.Join(chart, chartMetadataFile))
+	} else {
+		content, err = readArchivedChartMetadata(chart)
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "reading %s of %s", chartMetadataFile, chart)
+	}
+
+	metadata := chartMetadata{}
+	if err := yaml.Unmarshal(content, &metadata); err != nil {
+		return "", errors.Wrapf(err, "parsing %s of %s", chartMetadataFile, chart)
+	}
+	if metadata.Name == "" {
+		return "", errors.Errorf("%s of %s has no name", chartMetadataFile, chart)
+	}
+	if metadata.APIVersion != "v2" {
+		log.Warnf("Chart %s has apiVersion %q, helm 3 charts use v2.", metadata.Name, metadata.APIVersion)
+	}
+	return metadata.Name, nil
+}
+
+// readArchivedChartMetadata returns the top level Chart.yaml of a chart archive, eg: nginx/Chart.yaml
+func readArchivedChartMetadata(archive string) ([]byte, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.Errorf("no %s found", chartMetadataFile)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Clean(strings.Replace(header.Name, "\\", "/", -1))
+		if path.Base(name) == chartMetadataFile && strings.Count(name, "/") == 1 {
+			return ioutil.ReadAll(tr)
+		}
+	}
+}
+
+// InstallArgs returns the helm arguments installing or upgrading the chart release, armada waits for its resources
+func InstallArgs(kubeConfigPath, chart string, opts InstallOptions) []string {
+	args := []string{"upgrade", opts.ReleaseName, chart, "--install",
+		"--kubeconfig", kubeConfigPath, "--namespace", opts.Namespace, "--timeout", opts.Timeout.String()}
+	for _, valuesFile := range opts.ValuesFiles {
+		args = append(args, "--values", valuesFile)
+	}
+	return args
+}
+
+// Install installs or upgrades the chart release in the cluster with helm 3, see Manifest for the release resources
+func Install(clName, helmPath, kubeConfigPath, chart string, opts InstallOptions) error {
+	helmPath, err := lookPath(helmPath)
+	if err != nil {
+		return err
+	}
+
+	var output bytes.Buffer
+	cmd := exec.Command(helmPath, InstallArgs(kubeConfigPath, chart, opts)...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	log.Debugf("Installing %s release %s in %s: %s %s", chart, opts.ReleaseName, clName, helmPath, strings.Join(cmd.Args[1:], " "))
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "installing %s release %s: %s", chart, opts.ReleaseName, output.String())
+	}
+	log.Debugf("%s", output.String())
+	return nil
+}
+
+// UninstallArgs returns the helm arguments uninstalling the release
+func UninstallArgs(kubeConfigPath, releaseName, namespace string, timeout time.Duration) []string {
+	return []string{"uninstall", releaseName, "--kubeconfig", kubeConfigPath, "--namespace", namespace, "--timeout", timeout.String()}
+}
+
+// Uninstall uninstalls the release from the cluster with helm 3, helm deletes the release resources
+func Uninstall(clName, helmPath, kubeConfigPath, releaseName, namespace string, timeout time.Duration) error {
+	helmPath, err := lookPath(helmPath)
+	if err != nil {
+		return err
+	}
+
+	var output bytes.Buffer
+	cmd := exec.Command(helmPath, UninstallArgs(kubeConfigPath, releaseName, namespace, timeout)...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	log.Debugf("Uninstalling release %s from %s: %s %s", releaseName, clName, helmPath, strings.Join(cmd.Args[1:], " "))
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "uninstalling release %s: %s", releaseName, output.String())
+	}
+	log.Debugf("%s", output.String())
+	return nil
+}
+
+// Manifest returns the manifest of the resources of the release
+func Manifest(helmPath, kubeConfigPath, releaseName, namespace string) (string, error) {
+	helmPath, err := lookPath(helmPath)
+	if err != nil {
+		return "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(helmPath, "get", "manifest", releaseName, "--kubeconfig", kubeConfigPath, "--namespace", namespace)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "getting the manifest of release %s: %s", releaseName, stderr.String())
+	}
+	return stdout.String(), nil
+}
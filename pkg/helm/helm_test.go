@@ -0,0 +1,148 @@
+package helm_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dimaunx/armada/pkg/helm"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestHelm(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Helm test suite")
+}
+
+// releaseManifest is the manifest the fake helm prints for helm get manifest
+const releaseManifest = "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: demo\n"
+
+// writeFakeHelm writes a helm script printing the version and the release manifest and recording the arguments
+func writeFakeHelm(dir, version string) string {
+	helmPath := filepath.Join(dir, "helm")
+	script := "#!/bin/sh\nif [ \"$1\" = version ]; then echo " + version + "; exit 0; fi\necho \"$@\" > " + filepath.Join(dir, "args") + "\n" +
+		"if [ \"$1\" = get ]; then printf '%s' '" + releaseManifest + "'; fi\n"
+	Ω(ioutil.WriteFile(helmPath, []byte(script), 0755)).Should(Succeed())
+	return helmPath
+}
+
+// archiveChart writes the chart directory to a .tgz archive with the chart name as the top level directory
+func archiveChart(chartDir, archive string) {
+	f, err := os.Create(archive)
+	Ω(err).ShouldNot(HaveOccurred())
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	defer gz.Close()
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+
+	err = filepath.Walk(chartDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(filepath.Dir(chartDir), path)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: filepath.ToSlash(rel), Mode: 0644, Size: int64(len(content))}); err != nil {
+			return err
+		}
+		_, err = tw.Write(content)
+		return err
+	})
+	Ω(err).ShouldNot(HaveOccurred())
+}
+
+var _ = Describe("Helm tests", func() {
+
+	var tmpDir string
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "armada-helm")
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	Context("Charts", func() {
+		It("Should return the name of a chart directory", func() {
+			name, err := helm.ChartName(filepath.Join("testdata", "demo"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(name).Should(Equal("demo"))
+		})
+		It("Should return the name of a chart archive", func() {
+			archive := filepath.Join(tmpDir, "demo-0.1.0.tgz")
+			archiveChart(filepath.Join("testdata", "demo"), archive)
+
+			name, err := helm.ChartName(archive)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(name).Should(Equal("demo"))
+		})
+		It("Should fail for a directory without Chart.yaml", func() {
+			_, err := helm.ChartName("testdata")
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+	Context("Helm binary", func() {
+		It("Should accept helm 3 only", func() {
+			Ω(helm.CheckVersion(writeFakeHelm(tmpDir, "v3.0.2+g19e47ee"))).Should(Succeed())
+			Ω(helm.CheckVersion(writeFakeHelm(tmpDir, "v2.16.1+gbbdfe5e"))).ShouldNot(Succeed())
+		})
+		It("Should fail clearly if the helm binary is not found", func() {
+			err := helm.CheckVersion(filepath.Join(tmpDir, "missing"))
+			Ω(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("helm 3 is required"))
+
+			err = helm.Install("cluster1", "armada-missing-helm", "/tmp/kind-config-cluster1", "testdata/demo", helm.InstallOptions{})
+			Ω(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring(`helm binary "armada-missing-helm" was not found`))
+		})
+		It("Should install the release without waiting for its resources", func() {
+			opts := helm.InstallOptions{
+				ReleaseName: "demo",
+				Namespace:   "demo",
+				ValuesFiles: []string{"01-values.yaml", "02-cluster1.yaml"},
+				Timeout:     5 * time.Minute,
+			}
+			err := helm.Install("cluster1", writeFakeHelm(tmpDir, "v3.0.2"), "/tmp/kind-config-cluster1", "testdata/demo", opts)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			args, err := ioutil.ReadFile(filepath.Join(tmpDir, "args"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(strings.TrimSpace(string(args))).Should(Equal("upgrade demo testdata/demo --install --kubeconfig /tmp/kind-config-cluster1 " +
+				"--namespace demo --timeout 5m0s --values 01-values.yaml --values 02-cluster1.yaml"))
+		})
+		It("Should uninstall the release", func() {
+			err := helm.Uninstall("cluster1", writeFakeHelm(tmpDir, "v3.0.2"), "/tmp/kind-config-cluster1", "demo", "demo", 5*time.Minute)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			args, err := ioutil.ReadFile(filepath.Join(tmpDir, "args"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(strings.TrimSpace(string(args))).Should(Equal("uninstall demo --kubeconfig /tmp/kind-config-cluster1 --namespace demo --timeout 5m0s"))
+
+			err = helm.Uninstall("cluster1", "armada-missing-helm", "/tmp/kind-config-cluster1", "demo", "demo", time.Minute)
+			Ω(err).Should(HaveOccurred())
+		})
+		It("Should return the manifest of the release", func() {
+			manifest, err := helm.Manifest(writeFakeHelm(tmpDir, "v3.0.2"), "/tmp/kind-config-cluster1", "demo", "demo")
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(manifest).Should(Equal(releaseManifest))
+
+			args, err := ioutil.ReadFile(filepath.Join(tmpDir, "args"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(strings.TrimSpace(string(args))).Should(Equal("get manifest demo --kubeconfig /tmp/kind-config-cluster1 --namespace demo"))
+		})
+	})
+})
@@ -0,0 +1,281 @@
+package image
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+// OCI image layout annotations of the image name
+const (
+	annotationContainerdName = "io.containerd.image.name"
+	annotationRefName        = "org.opencontainers.image.ref.name"
+)
+
+// OCI media types of image indexes, docker manifest lists are handled the same way
+var indexMediaTypes = map[string]bool{
+	"application/vnd.oci.image.index.v1+json":                   true,
+	"application/vnd.docker.distribution.manifest.list.v2+json": true,
+}
+
+// dockerManifest is an entry of the manifest.json of a docker save archive
+type dockerManifest struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+}
+
+// ociDescriptor is a descriptor of an OCI index or manifest
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+	Platform    *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+	} `json:"platform"`
+}
+
+// ociIndex is an OCI image index, eg: the index.json of an image layout
+type ociIndex struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+// ociManifest is the part of an OCI image manifest armada uses
+type ociManifest struct {
+	Config ociDescriptor `json:"config"`
+}
+
+// fileReader reads a file of an archive by its path in the archive
+type fileReader func(name string) ([]byte, error)
+
+// ArchiveImages returns the images, names and ids, of a docker save archive or an OCI image layout, either a
+// directory or a tar. All the images must have a name, the OCI ref name annotation is used only if it is a full reference.
+func ArchiveImages(path string) ([]Image, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var read fileReader
+	if info.IsDir() {
+		read = func(name string) ([]byte, error) {
+			return ioutil.ReadFile(filepath.Join(path, filepath.FromSlash(name)))
+		}
+	} else {
+		read = func(name string) ([]byte, error) {
+			return readTarFile(path, name)
+		}
+	}
+
+	// docker save archives of newer docker versions are OCI layouts too, manifest.json has the repo tags of all of them
+	manifestFile, err := read("manifest.json")
+	if err == nil {
+		images, err := dockerArchiveImages(manifestFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s", path)
+		}
+		return images, checkNamed(path, images)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	indexFile, err := read("index.json")
+	if os.IsNotExist(err) {
+		return nil, errors.Errorf("%s is neither a docker save archive nor an OCI image layout", path)
+	} else if err != nil {
+		return nil, err
+	}
+	images, err := ociLayoutImages(indexFile, read)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", path)
+	}
+	return images, checkNamed(path, images)
+}
+
+// checkNamed returns an error listing the images without a name, they can not be checked on the nodes or imported by name
+func checkNamed(path string, images []Image) error {
+	var unnamed []string
+	for _, img := range images {
+		if img.Name == "" {
+			unnamed = append(unnamed, img.ID)
+		}
+	}
+	if len(unnamed) > 0 {
+		return errors.Errorf("%s has images without a repository and tag: %s, save them by name, eg: docker save repo:tag, "+
+			"or set the %s annotation to the full image name", path, strings.Join(unnamed, ", "), annotationContainerdName)
+	}
+	return nil
+}
+
+// dockerArchiveImages returns the images of the manifest.json of a docker save archive, the image id is the config digest
+func dockerArchiveImages(manifestFile []byte) ([]Image, error) {
+	var manifests []dockerManifest
+	if err := json.Unmarshal(manifestFile, &manifests); err != nil {
+		return nil, errors.Wrap(err, "parsing manifest.json")
+	}
+
+	var images []Image
+	for _, manifest := range manifests {
+		// the config is <hex>.json in older archives and blobs/sha256/<hex> in newer ones
+		id := "sha256:" + strings.TrimSuffix(filepath.Base(manifest.Config), ".json")
+		if len(manifest.RepoTags) == 0 {
+			images = append(images, Image{ID: id})
+		}
+		for _, tag := range manifest.RepoTags {
+			images = append(images, Image{Name: tag, ID: id})
+		}
+	}
+	return images, nil
+}
+
+// ociLayoutImages returns the images of the index.json of an OCI image layout, the image id is the config digest of the
+// manifest of the current platform
+func ociLayoutImages(indexFile []byte, read fileReader) ([]Image, error) {
+	index := ociIndex{}
+	if err := json.Unmarshal(indexFile, &index); err != nil {
+		return nil, errors.Wrap(err, "parsing index.json")
+	}
+
+	var images []Image
+	for _, descriptor := range index.Manifests {
+		manifest, err := resolveManifest(descriptor, read)
+		if err != nil {
+			return nil, err
+		}
+
+		name := descriptor.Annotations[annotationContainerdName]
+		if name == "" && isFullReference(descriptor.Annotations[annotationRefName]) {
+			name = descriptor.Annotations[annotationRefName]
+		}
+		images = append(images, Image{Name: name, ID: manifest.Config.Digest})
+	}
+	return images, nil
+}
+
+// isFullReference returns if the OCI ref name is an image name with a tag or a digest, eg: docker.io/library/alpine:3.10.
+// Most layouts set the ref name to the tag only, eg: latest, which is not usable as an image name.
+func isFullReference(refName string) bool {
+	named, err := reference.ParseNormalizedNamed(refName)
+	if err != nil {
+		return false
+	}
+	_, tagged := named.(reference.Tagged)
+	_, digested := named.(reference.Digested)
+	return tagged || digested
+}
+
+// resolveManifest reads the manifest of the descriptor, for an index the manifest of the current platform or the first one
+func resolveManifest(descriptor ociDescriptor, read fileReader) (*ociManifest, error) {
+	content, err := read(blobPath(descriptor.Digest))
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading blob %s", descriptor.Digest)
+	}
+
+	if !indexMediaTypes[descriptor.MediaType] {
+		manifest := &ociManifest{}
+		if err := json.Unmarshal(content, manifest); err != nil {
+			return nil, errors.Wrapf(err, "parsing manifest %s", descriptor.Digest)
+		}
+		return manifest, nil
+	}
+
+	index := ociIndex{}
+	if err := json.Unmarshal(content, &index); err != nil {
+		return nil, errors.Wrapf(err, "parsing index %s", descriptor.Digest)
+	}
+	if len(index.Manifests) == 0 {
+		return nil, errors.Errorf("index %s has no manifests", descriptor.Digest)
+	}
+	selected := index.Manifests[0]
+	for _, m := range index.Manifests {
+		if m.Platform != nil && m.Platform.OS == "linux" && m.Platform.Architecture == runtime.GOARCH {
+			selected = m
+			break
+		}
+	}
+	return resolveManifest(selected, read)
+}
+
+// blobPath returns the path of a blob in an OCI image layout, eg: blobs/sha256/<hex>
+func blobPath(digest string) string {
+	return "blobs/" + strings.Replace(digest, ":", "/", 1)
+}
+
+// readTarFile returns the content of a file in a tar archive, entries before it are skipped without reading them
+func readTarFile(archivePath, name string) ([]byte, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := tar.NewReader(f)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "reading %s", archivePath)
+		}
+		if strings.TrimPrefix(header.Name, "./") == name {
+			return ioutil.ReadAll(reader)
+		}
+	}
+}
+
+// TarDir writes the directory, eg: an OCI image layout, to a temp tar file and returns its path
+func TarDir(dir string) (string, error) {
+	tmpFile, err := ioutil.TempFile("", "image-layout-*.tar")
+	if err != nil {
+		return "", err
+	}
+	defer tmpFile.Close()
+
+	writer := tar.NewWriter(tmpFile)
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := writer.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(writer, f)
+		return err
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return "", errors.Wrapf(err, "archiving %s", dir)
+	}
+	return tmpFile.Name(), nil
+}
@@ -0,0 +1,183 @@
+package image_test
+
+import (
+	"archive/tar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/dimaunx/armada/pkg/image"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// writeTar writes the files to a tar archive
+func writeTar(path string, files map[string]string) {
+	f, err := os.Create(path)
+	Ω(err).ShouldNot(HaveOccurred())
+	defer f.Close()
+
+	writer := tar.NewWriter(f)
+	for name, content := range files {
+		err = writer.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))})
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = writer.Write([]byte(content))
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+	Ω(writer.Close()).ShouldNot(HaveOccurred())
+}
+
+// ociLayout is an OCI image layout of a multi platform nginx image
+var ociLayout = map[string]string{
+	"oci-layout": `{"imageLayoutVersion":"1.0.0"}`,
+	"index.json": `{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.index.v1+json","digest":"sha256:1111",
+"annotations":{"io.containerd.image.name":"docker.io/library/nginx:alpine","org.opencontainers.image.ref.name":"alpine"}}]}`,
+	"blobs/sha256/1111": `{"schemaVersion":2,"manifests":[
+{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:2222","platform":{"architecture":"s390x","os":"linux"}},
+{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:3333","platform":{"architecture":"amd64","os":"linux"}}]}`,
+	"blobs/sha256/2222": `{"schemaVersion":2,"config":{"mediaType":"application/vnd.oci.image.config.v1+json","digest":"sha256:cccc"}}`,
+	"blobs/sha256/3333": `{"schemaVersion":2,"config":{"mediaType":"application/vnd.oci.image.config.v1+json","digest":"sha256:dddd"}}`,
+}
+
+var _ = Describe("image archive tests", func() {
+	var dir string
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "image-archive")
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("Should read the images of a docker save archive", func() {
+		archivePath := filepath.Join(dir, "images.tar")
+		writeTar(archivePath, map[string]string{
+			"manifest.json": `[{"Config":"aaaa.json","RepoTags":["alpine:latest","alpine:3.10"],"Layers":["l1/layer.tar"]},
+{"Config":"blobs/sha256/bbbb","RepoTags":["busybox:1.31"],"Layers":[]}]`,
+			"aaaa.json":    "{}",
+			"l1/layer.tar": "layer",
+		})
+
+		images, err := image.ArchiveImages(archivePath)
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(images).Should(Equal([]image.Image{
+			{Name: "alpine:latest", ID: "sha256:aaaa"},
+			{Name: "alpine:3.10", ID: "sha256:aaaa"},
+			{Name: "busybox:1.31", ID: "sha256:bbbb"},
+		}))
+	})
+	It("Should fail on untagged images of a docker save archive", func() {
+		archivePath := filepath.Join(dir, "images.tar")
+		writeTar(archivePath, map[string]string{
+			"manifest.json": `[{"Config":"aaaa.json","RepoTags":["alpine:latest"]},{"Config":"bbbb.json","RepoTags":null}]`,
+		})
+
+		_, err := image.ArchiveImages(archivePath)
+		Ω(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("images without a repository and tag: sha256:bbbb"))
+	})
+	It("Should use the OCI ref name only if it is a full reference", func() {
+		layout := func(refName string) string {
+			archivePath := filepath.Join(dir, "layout.tar")
+			writeTar(archivePath, map[string]string{
+				"index.json": `{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json",
+"digest":"sha256:3333","annotations":{"org.opencontainers.image.ref.name":"` + refName + `"}}]}`,
+				"blobs/sha256/3333": ociLayout["blobs/sha256/3333"],
+			})
+			return archivePath
+		}
+
+		images, err := image.ArchiveImages(layout("docker.io/library/alpine:3.10"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(images).Should(Equal([]image.Image{{Name: "docker.io/library/alpine:3.10", ID: "sha256:dddd"}}))
+
+		for _, tagOnly := range []string{"latest", "alpine"} {
+			_, err = image.ArchiveImages(layout(tagOnly))
+			Ω(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("images without a repository and tag: sha256:dddd"))
+		}
+	})
+	It("Should read the images of an OCI image layout tar and directory", func() {
+		archivePath := filepath.Join(dir, "nginx.tar")
+		writeTar(archivePath, ociLayout)
+
+		images, err := image.ArchiveImages(archivePath)
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(images).Should(HaveLen(1))
+		Expect(images[0].Name).Should(Equal("docker.io/library/nginx:alpine"))
+		Expect(images[0].ID).Should(BeElementOf("sha256:cccc", "sha256:dddd"))
+
+		layoutDir := filepath.Join(dir, "layout")
+		for name, content := range ociLayout {
+			path := filepath.Join(layoutDir, filepath.FromSlash(name))
+			Ω(os.MkdirAll(filepath.Dir(path), 0755)).ShouldNot(HaveOccurred())
+			Ω(ioutil.WriteFile(path, []byte(content), 0644)).ShouldNot(HaveOccurred())
+		}
+		dirImages, err := image.ArchiveImages(layoutDir)
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(dirImages).Should(Equal(images))
+
+		// the directory is archived to a tar with the same images
+		tarPath, err := image.TarDir(layoutDir)
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.Remove(tarPath)
+		tarImages, err := image.ArchiveImages(tarPath)
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(tarImages).Should(Equal(images))
+	})
+	It("Should index the image cache archives by the normalized image names", func() {
+		writeTar(filepath.Join(dir, "aaaa.tar"), map[string]string{
+			"manifest.json": `[{"Config":"aaaa.json","RepoTags":["calico/node:v3.9.3"]}]`,
+		})
+		writeTar(filepath.Join(dir, "nginx.tar"), ociLayout)
+		writeTar(filepath.Join(dir, "broken.tar"), map[string]string{"README": "not an image"})
+		Ω(ioutil.WriteFile(filepath.Join(dir, ".save-123"), []byte("partial"), 0644)).ShouldNot(HaveOccurred())
+
+		archives, err := image.CachedArchives(dir)
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(archives).Should(Equal(map[string]string{
+			"docker.io/calico/node:v3.9.3":   filepath.Join(dir, "aaaa.tar"),
+			"docker.io/library/nginx:alpine": filepath.Join(dir, "nginx.tar"),
+		}))
+
+		archives, err = image.CachedArchives(filepath.Join(dir, "missing"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(archives).Should(BeEmpty())
+	})
+	It("Should evict the cached archives replaced by a new one", func() {
+		writeTar(filepath.Join(dir, "aaaa.tar"), map[string]string{
+			"manifest.json": `[{"Config":"aaaa.json","RepoTags":["alpine:latest"]}]`,
+		})
+		writeTar(filepath.Join(dir, "multi.tar"), map[string]string{
+			"manifest.json": `[{"Config":"cccc.json","RepoTags":["nginx:alpine"]},{"Config":"dddd.json","RepoTags":["busybox:1.31"]}]`,
+		})
+		writeTar(filepath.Join(dir, "eeee.tar"), map[string]string{
+			"manifest.json": `[{"Config":"eeee.json","RepoTags":["calico/node:v3.9.3"]}]`,
+		})
+		writeTar(filepath.Join(dir, "bbbb.tar"), map[string]string{
+			"manifest.json": `[{"Config":"bbbb.json","RepoTags":["alpine:latest"]}]`,
+		})
+
+		// alpine was rebuilt and nginx is cached again on its own
+		err := image.EvictReplaced(dir, filepath.Join(dir, "bbbb.tar"), []image.Image{{Name: "docker.io/library/alpine:latest", ID: "sha256:bbbb"}})
+		Ω(err).ShouldNot(HaveOccurred())
+		err = image.EvictReplaced(dir, filepath.Join(dir, "cccc.tar"), []image.Image{{Name: "nginx:alpine", ID: "sha256:cccc"}})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		archives, err := image.CachedArchives(dir)
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(archives).Should(Equal(map[string]string{
+			"docker.io/library/alpine:latest": filepath.Join(dir, "bbbb.tar"),
+			"docker.io/calico/node:v3.9.3":    filepath.Join(dir, "eeee.tar"),
+		}))
+	})
+	It("Should fail on an archive without a manifest", func() {
+		archivePath := filepath.Join(dir, "other.tar")
+		writeTar(archivePath, map[string]string{"README": "not an image"})
+
+		_, err := image.ArchiveImages(archivePath)
+		Ω(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("neither a docker save archive nor an OCI image layout"))
+	})
+})
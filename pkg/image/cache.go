@@ -0,0 +1,115 @@
+package image
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dimaunx/armada/pkg/imageref"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// CacheFileName returns the name of the cached archive of the images. The name is derived from the image names and ids,
+// so an archive is reused as long as the images do not change, while a rebuilt image or a new tag of an image gets a new
+// archive holding the requested names.
+func CacheFileName(images []Image) string {
+	var refs []string
+	for _, img := range images {
+		refs = append(refs, imageref.Normalize(img.Name)+"@"+img.ID)
+	}
+	sort.Strings(refs)
+	sum := sha256.Sum256([]byte(strings.Join(refs, ",")))
+	return hex.EncodeToString(sum[:]) + ".tar"
+}
+
+// SaveCached saves the images to a single archive in cacheDir unless it is already cached and returns the archive path.
+// The archives it replaces are evicted, see EvictReplaced.
+func SaveCached(ctx context.Context, dockerCli *dockerclient.Client, cacheDir string, images []Image) (string, error) {
+	archivePath := filepath.Join(cacheDir, CacheFileName(images))
+	if _, err := os.Stat(archivePath); err == nil {
+		log.Debugf("Using cached archive %q of %s.", archivePath, Names(images))
+		return archivePath, nil
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", err
+	}
+
+	// the archive is saved to a temp file first so an interrupted save never leaves a partial archive in the cache
+	tmpFile, err := ioutil.TempFile(cacheDir, ".save-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	imageBody, err := dockerCli.ImageSave(ctx, Names(images))
+	if err != nil {
+		return "", errors.Wrapf(err, "saving %s", Names(images))
+	}
+	defer imageBody.Close()
+
+	progress := NewProgress("saving "+strings.Join(Names(images), ", "), 0)
+	stop := progress.Report(progressInterval)
+	_, err = io.Copy(tmpFile, progress.Reader(imageBody))
+	stop()
+	if err != nil {
+		return "", errors.Wrapf(err, "saving %s", Names(images))
+	}
+	if err := tmpFile.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmpFile.Name(), archivePath); err != nil {
+		return "", err
+	}
+	log.Infof("✔ %s were saved to %q, %s.", Names(images), archivePath, progress)
+
+	if err := EvictReplaced(cacheDir, archivePath, images); err != nil {
+		log.Warnf("Failed to evict the replaced image cache archives: %v", err)
+	}
+	return archivePath, nil
+}
+
+// EvictReplaced removes the archives of cacheDir, other than archivePath, holding any of the images by name, either an older id of
+// a rebuilt image or the same image in an archive of other images. Each image name is then cached in a single archive,
+// images evicted along with a replaced one are saved again when they are loaded next.
+func EvictReplaced(cacheDir, archivePath string, images []Image) error {
+	names := map[string]bool{}
+	for _, img := range images {
+		names[imageref.Normalize(img.Name)] = true
+	}
+
+	entries, err := ioutil.ReadDir(cacheDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(cacheDir, entry.Name())
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".tar" || path == archivePath {
+			continue
+		}
+		cachedImages, err := ArchiveImages(path)
+		if err != nil {
+			continue
+		}
+		for _, img := range cachedImages {
+			if names[imageref.Normalize(img.Name)] {
+				log.Debugf("Evicting cached archive %q of %s, replaced by %q.", path, Names(cachedImages), archivePath)
+				if err := os.Remove(path); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package image
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/gobuffalo/packr/v2"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// NodeImageCatalogFile is the location of the node image catalog in the configs box
+const NodeImageCatalogFile = "images/node-images.yaml"
+
+// Local presence of a node image
+const (
+	NodeImageMissing   = "no"
+	NodeImagePresent   = "yes"
+	NodeImageTagOnly   = "tag only"
+	NodeImageUnchecked = "unknown"
+)
+
+// NodeImage is a kind node image for a kubernetes version
+type NodeImage struct {
+	// KubernetesVersion is the kubernetes version of the image
+	KubernetesVersion string `yaml:"kubernetesVersion"`
+
+	// Image is the image name and tag
+	Image string `yaml:"image"`
+
+	// Digest is the pinned image digest, empty if not known
+	Digest string `yaml:"digest"`
+}
+
+// NodeImageCatalog is a list of node images supported by the bundled kind version
+type NodeImageCatalog struct {
+	// Kind is the kind version the images were built for
+	Kind string `yaml:"kind"`
+
+	// Images are the node images, newest first
+	Images []NodeImage `yaml:"images"`
+}
+
+// Reference returns the image reference, pinned by digest if known
+func (n *NodeImage) Reference() string {
+	if n.Digest == "" {
+		return n.Image
+	}
+	return n.Image + "@" + n.Digest
+}
+
+// LoadNodeImageCatalog loads the node image catalog from the configs box
+func LoadNodeImageCatalog(box *packr.Box) (*NodeImageCatalog, error) {
+	catalogFile, err := box.Resolve(NodeImageCatalogFile)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := &NodeImageCatalog{}
+	if err := yaml.Unmarshal([]byte(catalogFile.String()), catalog); err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", NodeImageCatalogFile)
+	}
+
+	for _, img := range catalog.Images {
+		if _, err := semver.NewVersion(img.KubernetesVersion); err != nil {
+			return nil, errors.Wrapf(err, "invalid kubernetes version for image %s", img.Image)
+		}
+	}
+
+	sort.SliceStable(catalog.Images, func(i, j int) bool {
+		return semver.MustParse(catalog.Images[i].KubernetesVersion).GreaterThan(semver.MustParse(catalog.Images[j].KubernetesVersion))
+	})
+	return catalog, nil
+}
+
+// Find returns the node image for a kubernetes version. A major.minor version selects the newest patch release.
+func (c *NodeImageCatalog) Find(version string) (*NodeImage, error) {
+	version = strings.TrimPrefix(version, "v")
+	constraint, err := semver.NewConstraint("~" + version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid kubernetes version %q", version)
+	}
+
+	exact := strings.Count(version, ".") == 2
+	for i, img := range c.Images {
+		ver := semver.MustParse(img.KubernetesVersion)
+		if exact && ver.Original() == version || !exact && constraint.Check(ver) {
+			return &c.Images[i], nil
+		}
+	}
+
+	var supported []string
+	for _, img := range c.Images {
+		supported = append(supported, img.KubernetesVersion)
+	}
+	return nil, errors.Errorf("no node image for kubernetes version %q in kind %s catalog, supported versions: %s", version, c.Kind, strings.Join(supported, ", "))
+}
+
+// GetNodeImagePresence returns whether the node image exists locally, with the pinned digest or by tag only
+func GetNodeImagePresence(ctx context.Context, dockerCli *dockerclient.Client, img *NodeImage) (string, error) {
+	imageFilter := filters.NewArgs()
+	imageFilter.Add("reference", img.Image)
+	result, err := dockerCli.ImageList(ctx, types.ImageListOptions{
+		All:     false,
+		Filters: imageFilter,
+	})
+	if err != nil {
+		return NodeImageUnchecked, err
+	}
+	if len(result) == 0 {
+		return NodeImageMissing, nil
+	}
+	if img.Digest == "" {
+		return NodeImagePresent, nil
+	}
+
+	repository := img.Image[:strings.LastIndex(img.Image, ":")]
+	for _, summary := range result {
+		for _, repoDigest := range summary.RepoDigests {
+			if repoDigest == repository+"@"+img.Digest {
+				return NodeImagePresent, nil
+			}
+		}
+	}
+	return NodeImageTagOnly, nil
+}
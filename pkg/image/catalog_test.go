@@ -0,0 +1,54 @@
+package image_test
+
+import (
+	"github.com/dimaunx/armada/pkg/image"
+	"github.com/gobuffalo/packr/v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	kinddefaults "sigs.k8s.io/kind/pkg/apis/config/defaults"
+)
+
+var _ = Describe("node image catalog tests", func() {
+	box := packr.New("configs", "../../configs")
+
+	It("Should contain the kind default image", func() {
+		catalog, err := image.LoadNodeImageCatalog(box)
+		Ω(err).ShouldNot(HaveOccurred())
+		nodeImage, err := catalog.Find("1.16.3")
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(nodeImage.Reference()).Should(Equal(kinddefaults.Image))
+	})
+	It("Should pin every image by digest", func() {
+		catalog, err := image.LoadNodeImageCatalog(box)
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(catalog.Images).ShouldNot(BeEmpty())
+		for _, nodeImage := range catalog.Images {
+			Expect(nodeImage.Digest).Should(MatchRegexp("^sha256:[0-9a-f]{64}$"), "image %s", nodeImage.Image)
+			Expect(nodeImage.Reference()).Should(HavePrefix(nodeImage.Image + "@sha256:"))
+		}
+	})
+	It("Should select the newest patch release for minor version", func() {
+		catalog, err := image.LoadNodeImageCatalog(box)
+		Ω(err).ShouldNot(HaveOccurred())
+		nodeImage, err := catalog.Find("1.15")
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(nodeImage.Image).Should(Equal("kindest/node:v1.15.6"))
+		nodeImage, err = catalog.Find("v1.14")
+		Ω(err).ShouldNot(HaveOccurred())
+		Expect(nodeImage.Image).Should(Equal("kindest/node:v1.14.9"))
+	})
+	It("Should return the image by tag if digest is not known", func() {
+		nodeImage := &image.NodeImage{KubernetesVersion: "1.15.6", Image: "kindest/node:v1.15.6"}
+		Expect(nodeImage.Reference()).Should(Equal("kindest/node:v1.15.6"))
+	})
+	It("Should return an error for unsupported version", func() {
+		catalog, err := image.LoadNodeImageCatalog(box)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = catalog.Find("1.17")
+		Ω(err).Should(HaveOccurred())
+		_, err = catalog.Find("1.15.1")
+		Ω(err).Should(HaveOccurred())
+		_, err = catalog.Find("latest")
+		Ω(err).Should(HaveOccurred())
+	})
+})
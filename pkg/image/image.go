@@ -4,26 +4,133 @@ import (
 	"context"
 	"io"
 	"io/ioutil"
-	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
-	"sync"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
 
-	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/docker/distribution/reference"
 	dockerclient "github.com/docker/docker/client"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	kind "sigs.k8s.io/kind/pkg/cluster"
 	"sigs.k8s.io/kind/pkg/cluster/nodes"
-	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
 )
 
+// Image is an image name and its id
+type Image struct {
+	// Name is the image name/reference
+	Name string
+
+	// ID is the image id, eg: sha256:...
+	ID string
+}
+
+// NodeGroup is a group of nodes missing the same images
+type NodeGroup struct {
+	Images []Image
+	Nodes  []nodes.Node
+}
+
+// Names returns the names of the images, the id of images without a name
+func Names(images []Image) []string {
+	var names []string
+	for _, img := range images {
+		if img.Name == "" {
+			names = append(names, img.ID)
+			continue
+		}
+		names = append(names, img.Name)
+	}
+	return names
+}
+
+// GetLocalImages returns the local images by name/reference
+func GetLocalImages(ctx context.Context, dockerCli *dockerclient.Client, imageNames []string) ([]Image, error) {
+	var images []Image
+	for _, imageName := range imageNames {
+		id, err := GetLocalID(ctx, dockerCli, imageName)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, Image{Name: imageName, ID: id})
+	}
+	return images, nil
+}
+
+// FindLocalImages returns the images that exist locally, the names of the missing ones are logged and skipped
+func FindLocalImages(ctx context.Context, dockerCli *dockerclient.Client, imageNames []string) ([]Image, error) {
+	var images []Image
+	for _, imageName := range imageNames {
+		// manifests often use fully qualified names, eg: docker.io/calico/node, docker lists them by their familiar name
+		lookupName := imageName
+		if named, err := reference.ParseNormalizedNamed(imageName); err == nil {
+			lookupName = reference.FamiliarString(reference.TagNameOnly(named))
+		}
+		id, found, err := localID(ctx, dockerCli, lookupName)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			log.Infof("image %s not found locally.", imageName)
+			continue
+		}
+		images = append(images, Image{Name: imageName, ID: id})
+	}
+	return images, nil
+}
+
+// GroupNodesByMissingImages returns the nodes of the clusters that miss some of the images grouped by the images they miss,
+// so each distinct set of images is saved to a single archive once
+func GroupNodesByMissingImages(provider *kind.Provider, images []Image, clusters []string) ([]NodeGroup, error) {
+	var nodeOrder []string
+	nodesByName := map[string]nodes.Node{}
+	missing := map[string][]Image{}
+	for _, img := range images {
+		selectedNodes, err := GetNodesWithout(provider, img.Name, img.ID, clusters)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range selectedNodes {
+			if _, ok := nodesByName[node.String()]; !ok {
+				nodeOrder = append(nodeOrder, node.String())
+				nodesByName[node.String()] = node
+			}
+			missing[node.String()] = append(missing[node.String()], img)
+		}
+	}
+
+	var groups []NodeGroup
+	groupIndex := map[string]int{}
+	for _, nodeName := range nodeOrder {
+		key := strings.Join(Names(missing[nodeName]), ",")
+		i, ok := groupIndex[key]
+		if !ok {
+			i = len(groups)
+			groupIndex[key] = i
+			groups = append(groups, NodeGroup{Images: missing[nodeName]})
+		}
+		groups[i].Nodes = append(groups[i].Nodes, nodesByName[nodeName])
+	}
+	return groups, nil
+}
+
 // GetLocalID returns local image id by name/reference
 func GetLocalID(ctx context.Context, dockerCli *dockerclient.Client, imageName string) (string, error) {
+	id, found, err := localID(ctx, dockerCli, imageName)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", errors.Errorf("Image %s not found locally.", imageName)
+	}
+	return id, nil
+}
+
+// localID returns local image id by name/reference and if the image exists
+func localID(ctx context.Context, dockerCli *dockerclient.Client, imageName string) (string, bool, error) {
 	imageFilter := filters.NewArgs()
 	imageFilter.Add("reference", imageName)
 	result, err := dockerCli.ImageList(ctx, types.ImageListOptions{
@@ -31,43 +138,29 @@ func GetLocalID(ctx context.Context, dockerCli *dockerclient.Client, imageName s
 		Filters: imageFilter,
 	})
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 	if len(result) == 0 {
-		return "", errors.Errorf("Image %s not found locally.", imageName)
+		return "", false, nil
 	}
-	return result[0].ID, nil
+	return result[0].ID, true, nil
 }
 
 // GetNodesWithout return a list of nodes that don't have the image for multiple clusters
 func GetNodesWithout(provider *kind.Provider, imageName, localImageID string, clusters []string) ([]nodes.Node, error) {
+	statuses, err := GetNodeImageStatuses(provider, []Image{{Name: imageName, ID: localImageID}}, clusters)
+	if err != nil {
+		return nil, err
+	}
+
 	var selectedNodes []nodes.Node
-	for _, clName := range clusters {
-		known, err := cluster.IsKnown(clName, provider)
-		if err != nil {
-			return nil, err
-		}
-		if known {
-			nodeList, err := provider.ListInternalNodes(clName)
-			if err != nil {
-				return nil, err
-			}
-			if len(nodeList) == 0 {
-				return nil, errors.Errorf("no nodes found for cluster %q", clName)
-			}
-			// pick only the nodes that don't have the image
-			for _, node := range nodeList {
-				nodeImageID, err := nodeutils.ImageID(node, imageName)
-				if err != nil || nodeImageID != localImageID {
-					selectedNodes = append(selectedNodes, node)
-					log.Debugf("%s: image: %q with ID %q not present on node %q", clName, imageName, localImageID, node.String())
-				}
-				if nodeImageID == localImageID {
-					log.Infof("%s: ✔ image with ID %q already present on node %q", clName, nodeImageID, node.String())
-				}
-			}
+	for _, status := range statuses {
+		// pick only the nodes that don't have the image
+		if status.ID != localImageID {
+			selectedNodes = append(selectedNodes, status.Node)
+			log.Debugf("%s: image: %q with ID %q not present on node %q", status.Cluster, imageName, localImageID, status.Node.String())
 		} else {
-			return selectedNodes, errors.Errorf("cluster %q not found.", clName)
+			log.Infof("%s: ✔ image with ID %q already present on node %q", status.Cluster, status.ID, status.Node.String())
 		}
 	}
 	return selectedNodes, nil
@@ -105,21 +198,3 @@ func Save(ctx context.Context, dockerCli *dockerclient.Client, imageName string)
 	}
 	return tmpFilePath.Name(), nil
 }
-
-// LoadToNode loads an image to kubernetes node
-func LoadToNode(imageTarPath, imageName string, node nodes.Node, wg *sync.WaitGroup) error {
-	f, err := os.Open(imageTarPath)
-	if err != nil {
-		return errors.Wrapf(err, "failed to open an image: %s, location: %q", imageName, imageTarPath)
-	}
-	defer f.Close()
-
-	log.Debugf("loading image: %q, path: %q to node: %q ...", imageName, imageTarPath, node.String())
-	err = nodeutils.LoadImageArchive(node, f)
-	if err != nil {
-		return errors.Wrapf(err, "failed to loading image: %q, node %q", imageName, node.String())
-	}
-	log.Infof("✔ image: %q was loaded to node: %q.", imageName, node.String())
-	wg.Done()
-	return nil
-}
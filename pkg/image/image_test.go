@@ -27,11 +27,17 @@ var _ = Describe("image tests", func() {
 		ctx := context.Background()
 		dockerCli, _ := dockerclient.NewEnvClient()
 
-		BeforeSuite(func() {
+		// only the specs of this context need docker, the image is pulled once before the first of them
+		pulled := false
+		BeforeEach(func() {
+			if pulled {
+				return
+			}
 			reader, err := dockerCli.ImagePull(ctx, "docker.io/library/alpine:latest", types.ImagePullOptions{})
 			Ω(err).ShouldNot(HaveOccurred())
 			_, err = io.Copy(os.Stdout, reader)
 			Ω(err).ShouldNot(HaveOccurred())
+			pulled = true
 		})
 		It("Should return the correct local imageID", func() {
 			log.SetLevel(log.DebugLevel)
@@ -0,0 +1,116 @@
+package image
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dimaunx/armada/pkg/defaults"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	kind "sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+)
+
+// DefaultLoadConcurrency is the default number of nodes an archive is streamed to at once
+const DefaultLoadConcurrency = 4
+
+// LoadImages loads the images to the nodes of the clusters that miss them. Nodes missing the same images share one cached archive.
+func LoadImages(ctx context.Context, dockerCli *dockerclient.Client, provider *kind.Provider, images []Image, clusters []string, concurrency int) error {
+	groups, err := GroupNodesByMissingImages(provider, images, clusters)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		archivePath, err := SaveCached(ctx, dockerCli, defaults.HomePath(defaults.ImageCacheDir), group.Images)
+		if err != nil {
+			return err
+		}
+
+		label := strings.Join(Names(group.Images), ", ")
+		log.Infof("loading images: %s to nodes: %s ...", label, group.Nodes)
+		err = LoadArchive(archivePath, label, group.Nodes, concurrency)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadLocalImages loads the images that exist locally to the nodes of the clusters that miss them
+func LoadLocalImages(ctx context.Context, dockerCli *dockerclient.Client, provider *kind.Provider, imageNames []string, clusters []string, concurrency int) error {
+	images, err := FindLocalImages(ctx, dockerCli, imageNames)
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		log.Infof("none of the %d images exist locally.", len(imageNames))
+		return nil
+	}
+	return LoadImages(ctx, dockerCli, provider, images, clusters, concurrency)
+}
+
+// LoadArchive streams the image archive to the nodes, up to concurrency nodes at once, and reports the progress
+func LoadArchive(archivePath string, label string, nodeList []nodes.Node, concurrency int) error {
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return err
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	progress := NewProgress("loading "+label, info.Size()*int64(len(nodeList)))
+	stop := progress.Report(progressInterval)
+	defer stop()
+
+	var mutex sync.Mutex
+	var failures []string
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for _, node := range nodeList {
+		wg.Add(1)
+		go func(node nodes.Node) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			err := loadToNode(archivePath, node, progress)
+			if err != nil {
+				mutex.Lock()
+				failures = append(failures, err.Error())
+				mutex.Unlock()
+			}
+		}(node)
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return errors.Errorf("loading %s failed on %d of %d nodes: %s", label, len(failures), len(nodeList), strings.Join(failures, "; "))
+	}
+	log.Infof("✔ %s was loaded to %d nodes, %s.", label, len(nodeList), progress)
+	return nil
+}
+
+// loadToNode streams the image archive to the node
+func loadToNode(archivePath string, node nodes.Node, progress *Progress) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	start := time.Now()
+	log.Debugf("Loading %q to node %q ...", archivePath, node.String())
+	err = nodeutils.LoadImageArchive(node, progress.Reader(f))
+	if err != nil {
+		return errors.Wrapf(err, "node %q", node.String())
+	}
+	log.Debugf("✔ %q was loaded to node %q in %v.", archivePath, node.String(), time.Since(start).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,97 @@
+package image_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/dimaunx/armada/pkg/image"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/exec"
+)
+
+// fakeNode is a kind node recording the stdin of the commands run on it
+type fakeNode struct {
+	name  string
+	fail  bool
+	mutex sync.Mutex
+	stdin bytes.Buffer
+}
+
+func (n *fakeNode) String() string                     { return n.name }
+func (n *fakeNode) Role() (string, error)              { return "worker", nil }
+func (n *fakeNode) IP() (string, string, error)        { return "", "", nil }
+func (n *fakeNode) Command(string, ...string) exec.Cmd { return &fakeCmd{node: n} }
+
+// received returns the bytes streamed to the node
+func (n *fakeNode) received() []byte {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.stdin.Bytes()
+}
+
+// fakeCmd is a command of a fake node
+type fakeCmd struct {
+	node  *fakeNode
+	stdin io.Reader
+}
+
+func (c *fakeCmd) Run() error {
+	if c.node.fail {
+		return errors.New("ctr: failed to import")
+	}
+	c.node.mutex.Lock()
+	defer c.node.mutex.Unlock()
+	_, err := io.Copy(&c.node.stdin, c.stdin)
+	return err
+}
+func (c *fakeCmd) SetEnv(...string) exec.Cmd     { return c }
+func (c *fakeCmd) SetStdin(r io.Reader) exec.Cmd { c.stdin = r; return c }
+func (c *fakeCmd) SetStdout(io.Writer) exec.Cmd  { return c }
+func (c *fakeCmd) SetStderr(io.Writer) exec.Cmd  { return c }
+
+var _ = Describe("image load tests", func() {
+	It("Should name the cached archive by the image names and ids", func() {
+		alpine := image.Image{Name: "alpine:latest", ID: "sha256:aaaa"}
+		nginx := image.Image{Name: "nginx:alpine", ID: "sha256:bbbb"}
+
+		Expect(image.CacheFileName([]image.Image{alpine})).Should(MatchRegexp("^[0-9a-f]{64}\\.tar$"))
+		Expect(image.CacheFileName([]image.Image{alpine})).Should(Equal(image.CacheFileName([]image.Image{{Name: "docker.io/library/alpine:latest", ID: "sha256:aaaa"}})))
+		// a new tag of the same image is saved to a new archive holding the new name
+		Expect(image.CacheFileName([]image.Image{alpine})).ShouldNot(Equal(image.CacheFileName([]image.Image{{Name: "alpine:3.10", ID: "sha256:aaaa"}})))
+		Expect(image.CacheFileName([]image.Image{alpine})).ShouldNot(Equal(image.CacheFileName([]image.Image{{Name: "alpine:latest", ID: "sha256:cccc"}})))
+		Expect(image.CacheFileName([]image.Image{alpine, nginx})).Should(Equal(image.CacheFileName([]image.Image{nginx, alpine})))
+		Expect(image.CacheFileName([]image.Image{alpine, nginx})).ShouldNot(Equal(image.CacheFileName([]image.Image{alpine})))
+	})
+	It("Should format sizes in binary units", func() {
+		Expect(image.FormatBytes(512)).Should(Equal("512 B"))
+		Expect(image.FormatBytes(1536 * 1024 * 1024)).Should(Equal("1.5 GiB"))
+	})
+	It("Should stream the archive to all the nodes", func() {
+		dir, err := ioutil.TempDir("", "image-archive")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		content := bytes.Repeat([]byte("layer"), 100000)
+		archivePath := filepath.Join(dir, "images.tar")
+		Ω(ioutil.WriteFile(archivePath, content, 0644)).ShouldNot(HaveOccurred())
+
+		nodeList := []*fakeNode{{name: "cl1-worker"}, {name: "cl1-worker2"}, {name: "cl2-worker"}}
+		err = image.LoadArchive(archivePath, "demo", []nodes.Node{nodeList[0], nodeList[1], nodeList[2]}, 2)
+		Ω(err).ShouldNot(HaveOccurred())
+		for _, node := range nodeList {
+			Expect(node.received()).Should(Equal(content))
+		}
+
+		err = image.LoadArchive(archivePath, "demo", []nodes.Node{&fakeNode{name: "cl1-worker"}, &fakeNode{name: "cl2-worker", fail: true}}, 2)
+		Ω(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("1 of 2 nodes"))
+		Expect(err.Error()).Should(ContainSubstring("cl2-worker"))
+	})
+})
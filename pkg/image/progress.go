@@ -0,0 +1,91 @@
+package image
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// progressInterval is how often the progress of image transfers is reported
+const progressInterval = 5 * time.Second
+
+// Progress counts the bytes read by concurrent readers and reports the throughput
+type Progress struct {
+	// label describes the transfer, eg: the image names
+	label string
+
+	// total is the expected number of bytes, 0 if not known
+	total int64
+
+	// done is the number of bytes read so far, updated atomically
+	done int64
+
+	start time.Time
+}
+
+// NewProgress returns a progress of a transfer of total bytes, total is 0 if not known
+func NewProgress(label string, total int64) *Progress {
+	return &Progress{label: label, total: total, start: time.Now()}
+}
+
+// Reader returns a reader counting the bytes read from r
+func (p *Progress) Reader(r io.Reader) io.Reader {
+	return &countingReader{reader: r, progress: p}
+}
+
+// Report logs the progress every interval until the returned stop function is called
+func (p *Progress) Report(interval time.Duration) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				log.Infof("%s: %s", p.label, p)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// String returns the transferred bytes and the throughput, eg: 512.0 MiB / 1.5 GiB, 120.3 MiB/s
+func (p *Progress) String() string {
+	done := atomic.LoadInt64(&p.done)
+	throughput := FormatBytes(int64(float64(done)/time.Since(p.start).Seconds())) + "/s"
+	if p.total == 0 {
+		return fmt.Sprintf("%s, %s", FormatBytes(done), throughput)
+	}
+	return fmt.Sprintf("%s / %s, %s", FormatBytes(done), FormatBytes(p.total), throughput)
+}
+
+// FormatBytes returns the size in binary units, eg: 1.5 GiB
+func FormatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// countingReader adds the bytes read to the progress
+type countingReader struct {
+	reader   io.Reader
+	progress *Progress
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	atomic.AddInt64(&r.progress.done, int64(n))
+	return n, err
+}
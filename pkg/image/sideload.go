@@ -0,0 +1,123 @@
+package image
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dimaunx/armada/pkg/imageref"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	kind "sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+)
+
+// SideloadImages makes sure all the nodes of the cluster have the images before the manifests running them are applied,
+// eg: the cni images on hosts without registry access. Images missing on a node are loaded from the local docker daemon
+// or from an archive in cacheDir. If any of them is in neither it fails before loading anything with the list of them.
+func SideloadImages(ctx context.Context, dockerCli *dockerclient.Client, provider *kind.Provider, clName string, imageNames []string, cacheDir string, concurrency int) error {
+	nodeList, err := provider.ListInternalNodes(clName)
+	if err != nil {
+		return err
+	}
+
+	missing := map[string][]nodes.Node{}
+	var missingNames []string
+	for _, imageName := range imageNames {
+		for _, node := range nodeList {
+			if _, err := nodeutils.ImageID(node, imageName); err != nil {
+				missing[imageName] = append(missing[imageName], node)
+			}
+		}
+		if len(missing[imageName]) > 0 {
+			missingNames = append(missingNames, imageName)
+		} else {
+			log.Debugf("%s: ✔ image %s is present on all nodes", clName, imageName)
+		}
+	}
+	if len(missingNames) == 0 {
+		return nil
+	}
+
+	localImages, err := FindLocalImages(ctx, dockerCli, missingNames)
+	if err != nil {
+		return err
+	}
+	local := map[string]Image{}
+	for _, img := range localImages {
+		local[img.Name] = img
+	}
+
+	cached, err := CachedArchives(cacheDir)
+	if err != nil {
+		return err
+	}
+
+	var unavailable []string
+	for _, imageName := range missingNames {
+		if _, ok := local[imageName]; ok {
+			continue
+		}
+		if _, ok := cached[imageref.Normalize(imageName)]; ok {
+			continue
+		}
+		unavailable = append(unavailable, imageName)
+	}
+	if len(unavailable) > 0 {
+		return errors.Errorf("images %s are not on the nodes of %s, in the local docker daemon or in the image cache %s, "+
+			"pull them on a host with registry access and load them with 'docker load' or copy their archives to the image cache",
+			strings.Join(unavailable, ", "), clName, cacheDir)
+	}
+
+	for _, imageName := range missingNames {
+		archivePath, source := cached[imageref.Normalize(imageName)], "the image cache"
+		if img, ok := local[imageName]; ok {
+			archivePath, err = SaveCached(ctx, dockerCli, cacheDir, []Image{img})
+			if err != nil {
+				return err
+			}
+			source = "the local docker daemon"
+		}
+
+		log.Infof("%s: side loading image %s from %s to nodes: %s ...", clName, imageName, source, missing[imageName])
+		err = LoadArchive(archivePath, imageName, missing[imageName], concurrency)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CachedArchives returns the archives of the image cache by the normalized names of their images, see imageref.Normalize.
+// Archives that can not be read are skipped.
+func CachedArchives(cacheDir string) (map[string]string, error) {
+	archives := map[string]string{}
+	entries, err := ioutil.ReadDir(cacheDir)
+	if os.IsNotExist(err) {
+		return archives, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".tar" {
+			continue
+		}
+		archivePath := filepath.Join(cacheDir, entry.Name())
+		images, err := ArchiveImages(archivePath)
+		if err != nil {
+			log.Warnf("skipping image cache archive %s: %v", archivePath, err)
+			continue
+		}
+		for _, img := range images {
+			if img.Name != "" {
+				archives[imageref.Normalize(img.Name)] = archivePath
+			}
+		}
+	}
+	return archives, nil
+}
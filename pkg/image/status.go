@@ -0,0 +1,127 @@
+package image
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dimaunx/armada/pkg/cluster"
+	"github.com/pkg/errors"
+	kind "sigs.k8s.io/kind/pkg/cluster"
+	"sigs.k8s.io/kind/pkg/cluster/nodes"
+	"sigs.k8s.io/kind/pkg/cluster/nodeutils"
+)
+
+// Image states of a node compared to the local docker image
+const (
+	StateCurrent = "current"
+	StateStale   = "stale"
+	StateMissing = "missing"
+	StatePresent = "present"
+)
+
+// NodeImageStatus is the presence of an image on a node
+type NodeImageStatus struct {
+	// Cluster is the cluster name of the node
+	Cluster string
+
+	// Node is the cluster node
+	Node nodes.Node
+
+	// Image is the image name/reference
+	Image string
+
+	// ID is the image id on the node, empty if the image is not present
+	ID string
+}
+
+// State returns if the image is missing on the node, or present with the local id, current, or another id, stale.
+// Images without a local id are only present or missing.
+func (s *NodeImageStatus) State(localID string) string {
+	switch {
+	case s.ID == "":
+		return StateMissing
+	case localID == "":
+		return StatePresent
+	case s.ID == localID:
+		return StateCurrent
+	}
+	return StateStale
+}
+
+// GetNodeImageStatuses returns the status of every image on every node of the clusters
+func GetNodeImageStatuses(provider *kind.Provider, images []Image, clusters []string) ([]NodeImageStatus, error) {
+	var statuses []NodeImageStatus
+	for _, clName := range clusters {
+		known, err := cluster.IsKnown(clName, provider)
+		if err != nil {
+			return nil, err
+		}
+		if !known {
+			return nil, errors.Errorf("cluster %q not found.", clName)
+		}
+
+		nodeList, err := provider.ListInternalNodes(clName)
+		if err != nil {
+			return nil, err
+		}
+		if len(nodeList) == 0 {
+			return nil, errors.Errorf("no nodes found for cluster %q", clName)
+		}
+
+		for _, node := range nodeList {
+			for _, img := range images {
+				// the node image id is empty if the image is not present
+				nodeImageID, _ := nodeutils.ImageID(node, img.Name)
+				statuses = append(statuses, NodeImageStatus{Cluster: clName, Node: node, Image: img.Name, ID: nodeImageID})
+			}
+		}
+	}
+	return statuses, nil
+}
+
+// WriteStatusMatrix writes a table of the cluster nodes with the state of each image, stale images with their id on the node
+func WriteStatusMatrix(w io.Writer, images []Image, statuses []NodeImageStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(tw, "CLUSTER\tNODE\t%s\n", strings.Join(Names(images), "\t"))
+
+	localIDs := map[string]string{}
+	for _, img := range images {
+		localIDs[img.Name] = img.ID
+	}
+
+	var rows []string
+	cells := map[string]map[string]string{}
+	for i := range statuses {
+		status := &statuses[i]
+		row := status.Cluster + "\t" + status.Node.String()
+		if _, ok := cells[row]; !ok {
+			rows = append(rows, row)
+			cells[row] = map[string]string{}
+		}
+		state := status.State(localIDs[status.Image])
+		if state == StateStale {
+			state = fmt.Sprintf("%s (%s)", state, ShortID(status.ID))
+		}
+		cells[row][status.Image] = state
+	}
+
+	for _, row := range rows {
+		var states []string
+		for _, img := range images {
+			states = append(states, cells[row][img.Name])
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", row, strings.Join(states, "\t"))
+	}
+	return tw.Flush()
+}
+
+// ShortID returns the first 12 characters of the image id without the digest algorithm, like docker images does
+func ShortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
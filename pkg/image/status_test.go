@@ -0,0 +1,45 @@
+package image_test
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/dimaunx/armada/pkg/image"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("image status tests", func() {
+	It("Should compare the node image ids with the local ones", func() {
+		status := image.NodeImageStatus{ID: "sha256:aaaa"}
+		Expect(status.State("sha256:aaaa")).Should(Equal(image.StateCurrent))
+		Expect(status.State("sha256:bbbb")).Should(Equal(image.StateStale))
+		Expect(status.State("")).Should(Equal(image.StatePresent))
+
+		status = image.NodeImageStatus{}
+		Expect(status.State("sha256:aaaa")).Should(Equal(image.StateMissing))
+	})
+	It("Should write a matrix of the cluster nodes and the images", func() {
+		images := []image.Image{
+			{Name: "alpine:latest", ID: "sha256:aaaa"},
+			{Name: "nginx:alpine"},
+		}
+		worker1 := &fakeNode{name: "cl1-worker"}
+		worker2 := &fakeNode{name: "cl2-worker"}
+		stale := "sha256:0123456789abcdef0123"
+		statuses := []image.NodeImageStatus{
+			{Cluster: "cl1", Node: worker1, Image: "alpine:latest", ID: "sha256:aaaa"},
+			{Cluster: "cl1", Node: worker1, Image: "nginx:alpine", ID: "sha256:cccc"},
+			{Cluster: "cl2", Node: worker2, Image: "alpine:latest", ID: stale},
+			{Cluster: "cl2", Node: worker2, Image: "nginx:alpine"},
+		}
+
+		var b bytes.Buffer
+		Ω(image.WriteStatusMatrix(&b, images, statuses)).ShouldNot(HaveOccurred())
+		lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+		Expect(lines).Should(HaveLen(3))
+		Expect(strings.Fields(lines[0])).Should(Equal([]string{"CLUSTER", "NODE", "alpine:latest", "nginx:alpine"}))
+		Expect(strings.Fields(lines[1])).Should(Equal([]string{"cl1", "cl1-worker", "current", "present"}))
+		Expect(strings.Fields(lines[2])).Should(Equal([]string{"cl2", "cl2-worker", "stale", "(0123456789ab)", "missing"}))
+	})
+})
@@ -0,0 +1,75 @@
+package image
+
+import (
+	"context"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// rebuildActions are the docker image events that may point a tag to a new image id
+var rebuildActions = []string{"tag", "pull", "load", "import"}
+
+// Watch calls onChange with the new id of the images whenever one of their tags points to a new image id, eg: after a
+// rebuild. It blocks until the context is done or the docker event stream fails. Errors of onChange are logged.
+func Watch(ctx context.Context, dockerCli *dockerclient.Client, images []Image, onChange func(Image) error) error {
+	known := map[string]string{}
+	for _, img := range images {
+		known[img.Name] = img.ID
+	}
+
+	eventFilter := filters.NewArgs()
+	eventFilter.Add("type", events.ImageEventType)
+	for _, action := range rebuildActions {
+		eventFilter.Add("event", action)
+	}
+	messages, errs := dockerCli.Events(ctx, types.EventsOptions{Filters: eventFilter})
+
+	log.Infof("Watching images: %s for changes, press ctrl+c to stop ...", Names(images))
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errs:
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "watching docker events")
+		case message := <-messages:
+			log.Debugf("docker image event %s %s %v", message.Action, message.Actor.ID, message.Actor.Attributes)
+			for _, img := range changed(ctx, dockerCli, known) {
+				known[img.Name] = img.ID
+				if err := onChange(img); err != nil {
+					log.Errorf("reloading image %s: %v", img.Name, err)
+				}
+			}
+		}
+	}
+}
+
+// changed returns the images whose local id is not the known one, images that are missing locally are skipped
+func changed(ctx context.Context, dockerCli *dockerclient.Client, known map[string]string) []Image {
+	var names []string
+	for name := range known {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var images []Image
+	for _, name := range names {
+		localID, err := GetLocalID(ctx, dockerCli, name)
+		if err != nil {
+			log.Debugf("image %s: %v", name, err)
+			continue
+		}
+		if localID != known[name] {
+			images = append(images, Image{Name: name, ID: localID})
+		}
+	}
+	return images
+}
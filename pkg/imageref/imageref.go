@@ -0,0 +1,15 @@
+package imageref
+
+import (
+	"github.com/docker/distribution/reference"
+)
+
+// Normalize returns the fully qualified image reference with the default registry and tag, or the name as is if it is
+// invalid, eg: nginx is docker.io/library/nginx:latest
+func Normalize(imageName string) string {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return imageName
+	}
+	return reference.TagNameOnly(named).String()
+}
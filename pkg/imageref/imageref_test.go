@@ -0,0 +1,29 @@
+package imageref_test
+
+import (
+	"testing"
+
+	"github.com/dimaunx/armada/pkg/imageref"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestImageRef(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Image reference test suite")
+}
+
+var _ = Describe("Image reference tests", func() {
+	It("Should normalize the image names", func() {
+		for name, expected := range map[string]string{
+			"nginx":                          "docker.io/library/nginx:latest",
+			"calico/node:v3.9.3":             "docker.io/calico/node:v3.9.3",
+			"docker.io/library/alpine:3.10":  "docker.io/library/alpine:3.10",
+			"quay.io/coreos/flannel:v0.11.0": "quay.io/coreos/flannel:v0.11.0",
+			"localhost:5000/demo":            "localhost:5000/demo:latest",
+			"Invalid:Name":                   "Invalid:Name",
+		} {
+			Expect(imageref.Normalize(name)).Should(Equal(expected), name)
+		}
+	})
+})
@@ -0,0 +1,122 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gobuffalo/packr/v2"
+	"github.com/gobuffalo/packr/v2/file/resolver"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Template origins
+const (
+	// OriginEmbedded is a template compiled into armada
+	OriginEmbedded = "embedded"
+
+	// OriginOverride is a user template that shadows an embedded one
+	OriginOverride = "override"
+
+	// OriginAdded is a user template that does not exist in armada
+	OriginAdded = "added"
+)
+
+// Template is a file in the configs box
+type Template struct {
+	// Name is the template path inside the box, eg: tpl/cluster-config.yaml
+	Name string
+
+	// Origin is embedded, override or added
+	Origin string
+
+	// Path is the user file path, empty for embedded templates
+	Path string
+}
+
+// overlays holds the user templates registered in the boxes
+var overlays sync.Map
+
+// Overlay registers the files found in dir on top of the box. The files shadow the embedded templates with the same
+// path relative to dir, the files that do not exist in the box are added to it.
+func Overlay(box *packr.Box, dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return errors.Wrap(err, "templates directory")
+	}
+	if !info.IsDir() {
+		return errors.Errorf("templates directory %s is not a directory", dir)
+	}
+
+	var userTemplates []Template
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && path != dir {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		origin := OriginAdded
+		if box.Has(name) {
+			origin = OriginOverride
+		}
+		box.SetResolver(name, &resolver.Disk{Root: dir})
+		userTemplates = append(userTemplates, Template{Name: name, Origin: origin, Path: path})
+		log.Debugf("Template %s: %s from %s.", name, origin, path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	overlays.Store(box.Name, userTemplates)
+	return nil
+}
+
+// List returns all the templates of the box with their origin
+func List(box *packr.Box) []Template {
+	userTemplates := map[string]Template{}
+	if v, ok := overlays.Load(box.Name); ok {
+		for _, t := range v.([]Template) {
+			userTemplates[t.Name] = t
+		}
+	}
+
+	var result []Template
+	for _, name := range box.List() {
+		name = filepath.ToSlash(name)
+		if t, ok := userTemplates[name]; ok {
+			result = append(result, t)
+			delete(userTemplates, name)
+			continue
+		}
+		result = append(result, Template{Name: name, Origin: OriginEmbedded})
+	}
+	for _, t := range userTemplates {
+		result = append(result, t)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
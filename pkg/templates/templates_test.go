@@ -0,0 +1,77 @@
+package templates_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dimaunx/armada/pkg/templates"
+	"github.com/gobuffalo/packr/v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestTemplates(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Templates test suite")
+}
+
+var _ = Describe("templates tests", func() {
+	Context("User templates overlay", func() {
+		var templatesDir string
+
+		BeforeEach(func() {
+			var err error
+			templatesDir, err = ioutil.TempDir("", "armada-templates")
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+		AfterEach(func() {
+			_ = os.RemoveAll(templatesDir)
+		})
+		It("Should shadow embedded templates and add new ones", func() {
+			box := packr.New("templates-overlay", "../../configs")
+			embedded, err := box.FindString("tpl/cni/calico/3.9.3/calico-daemonset.yaml")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(os.MkdirAll(filepath.Join(templatesDir, "tpl/cni/calico/3.9.3"), os.ModePerm)).Should(Succeed())
+			Ω(ioutil.WriteFile(filepath.Join(templatesDir, "tpl/cni/calico/3.9.3/calico-daemonset.yaml"), []byte("patched"), 0644)).Should(Succeed())
+			Ω(ioutil.WriteFile(filepath.Join(templatesDir, "extra.yaml"), []byte("extra"), 0644)).Should(Succeed())
+			Ω(ioutil.WriteFile(filepath.Join(templatesDir, ".extra.yaml.swp"), []byte("swap"), 0644)).Should(Succeed())
+
+			err = templates.Overlay(box, templatesDir)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			actual, err := box.FindString("tpl/cni/calico/3.9.3/calico-daemonset.yaml")
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(actual).Should(Equal("patched"))
+			Expect(actual).ShouldNot(Equal(embedded))
+
+			actual, err = box.FindString("extra.yaml")
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(actual).Should(Equal("extra"))
+			Expect(box.Has(".extra.yaml.swp")).Should(BeFalse())
+
+			origins := map[string]templates.Template{}
+			for _, t := range templates.List(box) {
+				origins[t.Name] = t
+			}
+			Expect(origins["tpl/cni/calico/3.9.3/calico-daemonset.yaml"].Origin).Should(Equal(templates.OriginOverride))
+			Expect(origins["tpl/cni/calico/3.9.3/calico-daemonset.yaml"].Path).Should(Equal(filepath.Join(templatesDir, "tpl/cni/calico/3.9.3/calico-daemonset.yaml")))
+			Expect(origins["extra.yaml"].Origin).Should(Equal(templates.OriginAdded))
+			Expect(origins["tpl/cluster-config.yaml"].Origin).Should(Equal(templates.OriginEmbedded))
+			Expect(origins["tpl/cluster-config.yaml"].Path).Should(BeEmpty())
+		})
+		It("Should list only embedded templates without overlay", func() {
+			box := packr.New("templates-embedded", "../../configs")
+			for _, t := range templates.List(box) {
+				Expect(t.Origin).Should(Equal(templates.OriginEmbedded))
+			}
+		})
+		It("Should return an error if the templates directory does not exist", func() {
+			box := packr.New("templates-missing", "../../configs")
+			err := templates.Overlay(box, filepath.Join(templatesDir, "missing"))
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,232 @@
+package wait
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// unrecoverableReasons are the container waiting reasons a pod does not recover from without a change of its spec or image
+var unrecoverableReasons = map[string]bool{
+	"ImagePullBackOff":  true,
+	"ErrImagePull":      true,
+	"ErrImageNeverPull": true,
+	"InvalidImageName":  true,
+	"CrashLoopBackOff":  true,
+}
+
+// pullBackOffReasons are the waiting reasons of failed pulls that are retried, eg: a registry rate limit or a slow mirror
+var pullBackOffReasons = map[string]bool{
+	"ImagePullBackOff": true,
+	"ErrImagePull":     true,
+}
+
+// pullBackOffGracePeriod is how long after the pod started a container may keep failing to pull its image before it is
+// considered failed
+const pullBackOffGracePeriod = 2 * time.Minute
+
+// crashLoopRestarts is how many restarts a crash looping container gets before it is considered failed,
+// CNI and addon pods often restart a few times while the cluster is bootstrapping
+const crashLoopRestarts = 3
+
+// logTailLines is the number of log lines of a failing container attached to the diagnostics
+const logTailLines = 20
+
+// maxDiagnosedPods is the number of not ready pods diagnosed when a wait times out
+const maxDiagnosedPods = 3
+
+// FailingContainer returns the container of the pod stuck in an unrecoverable state and the reason, eg: InvalidImageName.
+// Pulls are retried for pullBackOffGracePeriod and crash loops for crashLoopRestarts before the container is reported.
+func FailingContainer(pod *corev1.Pod) (string, string, bool) {
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if status.State.Waiting == nil || !unrecoverableReasons[status.State.Waiting.Reason] {
+			continue
+		}
+		reason := status.State.Waiting.Reason
+		if reason == "CrashLoopBackOff" && status.RestartCount < crashLoopRestarts {
+			continue
+		}
+		if pullBackOffReasons[reason] && podAge(pod) < pullBackOffGracePeriod {
+			continue
+		}
+		return status.Name, reason, true
+	}
+	return "", "", false
+}
+
+// checkPods returns an error with the diagnostics of the first pod of the object stuck in an unrecoverable state
+func checkPods(clientSet kubernetes.Interface, obj *unstructured.Unstructured) error {
+	pods, err := podsOf(clientSet, obj)
+	if err != nil {
+		return err
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+		container, reason, failed := FailingContainer(pod)
+		if failed {
+			return errors.Errorf("%s %s is not going to be ready, container %s of pod %s is in %s\n%s",
+				obj.GetKind(), obj.GetName(), container, pod.Name, reason, Diagnose(clientSet, pod))
+		}
+	}
+	return nil
+}
+
+// diagnoseNotReady returns the diagnostics of the not ready pods of the object
+func diagnoseNotReady(clientSet kubernetes.Interface, obj *unstructured.Unstructured) string {
+	pods, err := podsOf(clientSet, obj)
+	if err != nil {
+		return fmt.Sprintf("unable to list pods: %v\n", err)
+	}
+
+	var diagnostics []string
+	for i := range pods {
+		if len(diagnostics) == maxDiagnosedPods {
+			break
+		}
+		if !podReady(&pods[i]) {
+			diagnostics = append(diagnostics, Diagnose(clientSet, &pods[i]))
+		}
+	}
+	return strings.Join(diagnostics, "")
+}
+
+// podAge returns the time since the pod was started, or created if it was not started yet
+func podAge(pod *corev1.Pod) time.Duration {
+	started := pod.CreationTimestamp
+	if pod.Status.StartTime != nil {
+		started = *pod.Status.StartTime
+	}
+	if started.IsZero() {
+		return 0
+	}
+	return time.Since(started.Time)
+}
+
+// podsOf returns the pods of a deployment, daemon set, stateful set or job, or the pod itself
+func podsOf(clientSet kubernetes.Interface, obj *unstructured.Unstructured) ([]corev1.Pod, error) {
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	switch obj.GetKind() {
+	case "Pod":
+		pod, err := clientSet.CoreV1().Pods(namespace).Get(obj.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return []corev1.Pod{*pod}, nil
+	case "Deployment", "DaemonSet", "StatefulSet", "Job":
+		selectorMap, found, err := unstructured.NestedMap(obj.Object, "spec", "selector")
+		if err != nil || !found {
+			return nil, err
+		}
+		labelSelector := &metav1.LabelSelector{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(selectorMap, labelSelector); err != nil {
+			return nil, errors.Wrapf(err, "parsing the selector of %s %s", obj.GetKind(), obj.GetName())
+		}
+		selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing the selector of %s %s", obj.GetKind(), obj.GetName())
+		}
+		if selector.Empty() {
+			return nil, nil
+		}
+		podList, err := clientSet.CoreV1().Pods(namespace).List(metav1.ListOptions{
+			LabelSelector: selector.String(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return podList.Items, nil
+	}
+	return nil, nil
+}
+
+// Diagnose returns the container statuses and the events of the pod and the last log lines of its restarted containers
+func Diagnose(clientSet kubernetes.Interface, pod *corev1.Pod) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "pod %s/%s on node %q is %s:\n", pod.Namespace, pod.Name, pod.Spec.NodeName, pod.Status.Phase)
+
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		fmt.Fprintf(&b, "  container %s (%s): %s, ready: %v, restarts: %d\n", status.Name, status.Image, containerState(status.State), status.Ready, status.RestartCount)
+		if status.LastTerminationState.Terminated != nil {
+			fmt.Fprintf(&b, "    last %s\n", containerState(status.LastTerminationState))
+		}
+	}
+
+	eventList, err := clientSet.CoreV1().Events(pod.Namespace).List(metav1.ListOptions{
+		FieldSelector: "involvedObject.kind=Pod,involvedObject.name=" + pod.Name,
+	})
+	if err != nil {
+		fmt.Fprintf(&b, "  unable to list events: %v\n", err)
+	} else {
+		events := eventList.Items
+		sort.SliceStable(events, func(i, j int) bool { return events[i].LastTimestamp.Before(&events[j].LastTimestamp) })
+		fmt.Fprintf(&b, "  events:\n")
+		for _, event := range events {
+			if event.InvolvedObject.Name != pod.Name {
+				continue
+			}
+			fmt.Fprintf(&b, "    %s %s (x%d): %s\n", event.Type, event.Reason, event.Count, strings.TrimSpace(event.Message))
+		}
+	}
+
+	for _, status := range statuses {
+		if status.RestartCount == 0 {
+			continue
+		}
+		tail := int64(logTailLines)
+		logs, err := clientSet.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			Container: status.Name,
+			Previous:  true,
+			TailLines: &tail,
+		}).DoRaw()
+		if err != nil {
+			fmt.Fprintf(&b, "  unable to get logs of %s: %v\n", status.Name, err)
+			continue
+		}
+		fmt.Fprintf(&b, "  last %d log lines of %s:\n", logTailLines, status.Name)
+		for _, line := range strings.Split(strings.TrimRight(string(logs), "\n"), "\n") {
+			fmt.Fprintf(&b, "    %s\n", line)
+		}
+	}
+	return b.String()
+}
+
+// containerState returns a short description of the container state
+func containerState(state corev1.ContainerState) string {
+	switch {
+	case state.Waiting != nil:
+		return strings.TrimSpace(fmt.Sprintf("waiting %s %s", state.Waiting.Reason, state.Waiting.Message))
+	case state.Terminated != nil:
+		return strings.TrimSpace(fmt.Sprintf("terminated %s with exit code %d %s", state.Terminated.Reason, state.Terminated.ExitCode, state.Terminated.Message))
+	case state.Running != nil:
+		return "running"
+	}
+	return "unknown"
+}
+
+// podReady returns if the pod is ready or completed
+func podReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded {
+		return true
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
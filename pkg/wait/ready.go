@@ -0,0 +1,158 @@
+package wait
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// readyConditions are the status conditions of arbitrary objects that mean the object is ready
+var readyConditions = []string{"Ready", "Available", "Established"}
+
+// IsReady returns if the object is ready and a short description of its state. Deployments, daemon sets and stateful sets
+// are ready when all their replicas are updated and ready, jobs when completed, pods when running and ready, services when
+// their endpoints have ready addresses. Other objects are ready when their Ready, Available or Established condition is
+// true or when they have no status conditions. A failed job or pod and a CRD with conflicting names return an error.
+func IsReady(obj *unstructured.Unstructured) (bool, string, error) {
+	if !observedGeneration(obj) {
+		return false, "waiting for the controller to observe the latest generation", nil
+	}
+
+	switch obj.GetKind() {
+	case "Deployment":
+		deployment := &appsv1.Deployment{}
+		if err := fromUnstructured(obj, deployment); err != nil {
+			return false, "", err
+		}
+		replicas := replicasOrDefault(deployment.Spec.Replicas)
+		status := deployment.Status
+		return status.UpdatedReplicas == replicas && status.ReadyReplicas == replicas && status.Replicas == replicas,
+			fmt.Sprintf("%d of %d replicas updated, %d ready", status.UpdatedReplicas, replicas, status.ReadyReplicas), nil
+	case "DaemonSet":
+		daemonSet := &appsv1.DaemonSet{}
+		if err := fromUnstructured(obj, daemonSet); err != nil {
+			return false, "", err
+		}
+		// a daemon set without matching nodes is ready with no pods, once its controller observed it
+		status := daemonSet.Status
+		if status.ObservedGeneration < daemonSet.Generation {
+			return false, "waiting for the controller to observe the latest generation", nil
+		}
+		return status.UpdatedNumberScheduled == status.DesiredNumberScheduled && status.NumberReady == status.DesiredNumberScheduled,
+			fmt.Sprintf("%d of %d pods updated, %d ready", status.UpdatedNumberScheduled, status.DesiredNumberScheduled, status.NumberReady), nil
+	case "StatefulSet":
+		statefulSet := &appsv1.StatefulSet{}
+		if err := fromUnstructured(obj, statefulSet); err != nil {
+			return false, "", err
+		}
+		replicas := replicasOrDefault(statefulSet.Spec.Replicas)
+		status := statefulSet.Status
+		return status.ReadyReplicas == replicas && status.UpdatedReplicas == replicas,
+			fmt.Sprintf("%d of %d replicas updated, %d ready", status.UpdatedReplicas, replicas, status.ReadyReplicas), nil
+	case "Job":
+		job := &batchv1.Job{}
+		if err := fromUnstructured(obj, job); err != nil {
+			return false, "", err
+		}
+		for _, condition := range job.Status.Conditions {
+			if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+				return false, "", errors.Errorf("job %s failed: %s", job.Name, condition.Message)
+			}
+			if condition.Type == batchv1.JobComplete && condition.Status == corev1.ConditionTrue {
+				return true, "completed", nil
+			}
+		}
+		return false, fmt.Sprintf("%d active, %d succeeded", job.Status.Active, job.Status.Succeeded), nil
+	case "Pod":
+		pod := &corev1.Pod{}
+		if err := fromUnstructured(obj, pod); err != nil {
+			return false, "", err
+		}
+		switch pod.Status.Phase {
+		case corev1.PodSucceeded:
+			return true, "succeeded", nil
+		case corev1.PodFailed:
+			return false, "", errors.Errorf("pod %s failed: %s", pod.Name, pod.Status.Message)
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+				return true, "running", nil
+			}
+		}
+		return false, fmt.Sprintf("phase %s", pod.Status.Phase), nil
+	case "Endpoints":
+		endpoints := &corev1.Endpoints{}
+		if err := fromUnstructured(obj, endpoints); err != nil {
+			return false, "", err
+		}
+		for _, subset := range endpoints.Subsets {
+			if len(subset.Addresses) > 0 {
+				return true, fmt.Sprintf("%d ready addresses", len(subset.Addresses)), nil
+			}
+		}
+		return false, "no ready addresses", nil
+	case "CustomResourceDefinition":
+		if status, message := condition(obj, "NamesAccepted"); status == string(corev1.ConditionFalse) {
+			return false, "", errors.Errorf("CustomResourceDefinition %s names were not accepted: %s", obj.GetName(), message)
+		}
+		status, _ := condition(obj, "Established")
+		return status == string(corev1.ConditionTrue), "waiting to be established", nil
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if len(conditions) == 0 {
+		return true, "no status conditions", nil
+	}
+	for _, conditionType := range readyConditions {
+		status, message := condition(obj, conditionType)
+		if status == string(corev1.ConditionTrue) {
+			return true, conditionType, nil
+		} else if status != "" {
+			return false, fmt.Sprintf("%s is %s: %s", conditionType, status, message), nil
+		}
+	}
+	return true, "no readiness conditions", nil
+}
+
+// observedGeneration returns false if the object status reports an older generation than the object
+func observedGeneration(obj *unstructured.Unstructured) bool {
+	observed, found, err := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if !found || err != nil {
+		return true
+	}
+	return observed >= obj.GetGeneration()
+}
+
+// condition returns the status and the message of the object status condition
+func condition(obj *unstructured.Unstructured, conditionType string) (string, string) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != conditionType {
+			continue
+		}
+		status, _ := condition["status"].(string)
+		message, _ := condition["message"].(string)
+		return status, message
+	}
+	return "", ""
+}
+
+// fromUnstructured converts the object to its typed struct
+func fromUnstructured(obj *unstructured.Unstructured, typed interface{}) error {
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, typed)
+	return errors.Wrapf(err, "converting %s %s", obj.GetKind(), obj.GetName())
+}
+
+// replicasOrDefault returns the replicas of a workload, 1 if not set
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
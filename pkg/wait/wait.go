@@ -2,88 +2,187 @@ package wait
 
 import (
 	"context"
+	"sync"
 	"time"
 
-	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/kubernetes"
-
-	"github.com/dimaunx/armada/pkg/defaults"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
 )
 
-// ForPodsRunning waits for pods to be running
-func ForPodsRunning(clName string, c kubernetes.Interface, namespace, selector string, replicas int) error {
-	ctx := context.Background()
-	log.Debugf("Waiting for pods to be running. label: %q, namespace: %q, replicas: %v, duration: %v, *types: %s.", selector, namespace, replicas, defaults.WaitDurationResources, clName)
-	podsContext, cancel := context.WithTimeout(ctx, defaults.WaitDurationResources)
-	wait.Until(func() {
-		podList, err := c.CoreV1().Pods(namespace).List(metav1.ListOptions{
-			LabelSelector: selector,
-			FieldSelector: "status.phase=Running",
-		})
-		if err == nil && len(podList.Items) == replicas {
-			log.Infof("✔ All pods with label: %q are running for %s.", selector, clName)
-			cancel()
-		} else {
-			log.Debugf("Still waiting for pods. label: %q, namespace: %q, replicas: %v, cluster: %s.", selector, namespace, replicas, clName)
-		}
-	}, 15*time.Second, podsContext.Done())
+// podCheckInterval is how often the pods of a workload are checked for unrecoverable states while waiting
+const podCheckInterval = 5 * time.Second
 
-	err := podsContext.Err()
-	if err != nil && err != context.Canceled {
-		return errors.Wrap(err, "Error waiting for pods to be running.")
-	}
-	return nil
-}
+// ForObjectsReady waits up to timeout for all the objects to be ready, eg: everything a manifest applied. The pods of
+// workloads are checked with clientSet, the wait fails early if a pod is stuck pulling its image or crash looping and
+// the error has the diagnostics of the pods. clientSet can be nil for objects without pods.
+func ForObjectsReady(clName string, clientSet kubernetes.Interface, client dynamic.Interface, mapper meta.RESTMapper, objects []*unstructured.Unstructured, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-// ForDeploymentReady waits for deployment roll out
-func ForDeploymentReady(clName string, c kubernetes.Interface, namespace, deploymentName string) error {
-	ctx := context.Background()
-	log.Debugf("Waiting up to %v for %s deployment roll out %s ...", defaults.WaitDurationResources, deploymentName, clName)
-	deploymentContext, cancel := context.WithTimeout(ctx, defaults.WaitDurationResources)
-	wait.Until(func() {
-		deployment, err := c.AppsV1().Deployments(namespace).Get(deploymentName, metav1.GetOptions{})
-		if err == nil {
-			if deployment.Status.ReadyReplicas == *deployment.Spec.Replicas {
-				log.Infof("✔ %s successfully deployed to %s, ready replicas: %v", deploymentName, clName, deployment.Status.ReadyReplicas)
-				cancel()
-			} else {
-				log.Infof("Still waiting for %s deployment for %s, ready replicas: %v", deploymentName, clName, deployment.Status.ReadyReplicas)
+	for _, obj := range objects {
+		live, err := untilReady(ctx, clName, clientSet, client, mapper, obj)
+		if err == wait.ErrWaitTimeout {
+			err = errors.Errorf("timed out after %v waiting for %s %s to be ready in %s", timeout, obj.GetKind(), obj.GetName(), clName)
+			if clientSet != nil && live != nil {
+				if diagnostics := diagnoseNotReady(clientSet, live); diagnostics != "" {
+					err = errors.Errorf("%s\n%s", err, diagnostics)
+				}
 			}
-		} else {
-			log.Debugf("Still waiting for %s deployment roll out %s ...", deploymentName, clName)
+			return err
+		} else if err != nil {
+			return err
 		}
-	}, 15*time.Second, deploymentContext.Done())
-	err := deploymentContext.Err()
-	if err != nil && err != context.Canceled {
-		return errors.Wrapf(err, "Error waiting for %s deployment roll out.", deploymentName)
 	}
 	return nil
 }
 
-// ForDaemonSetReady waits for daemon set roll out
-func ForDaemonSetReady(clName string, c kubernetes.Interface, namespace, daemonSetName string) error {
-	ctx := context.Background()
-	log.Debugf("Waiting up to %v for %s daemon set roll out %s ...", defaults.WaitDurationResources, daemonSetName, clName)
-	deploymentContext, cancel := context.WithTimeout(ctx, defaults.WaitDurationResources)
-	wait.Until(func() {
-		daemonSet, err := c.AppsV1().DaemonSets(namespace).Get(daemonSetName, metav1.GetOptions{})
-		if err == nil && daemonSet.Status.CurrentNumberScheduled > 0 {
-			if daemonSet.Status.NumberReady == daemonSet.Status.DesiredNumberScheduled {
-				log.Infof("✔ %s successfully rolled out to %s, ready replicas: %v", daemonSetName, clName, daemonSet.Status.NumberReady)
-				cancel()
-			} else {
-				log.Infof("Still waiting for %s daemon set roll out for %s, ready replicas: %v", daemonSetName, clName, daemonSet.Status.NumberReady)
+// ForReady waits up to timeout for the object to be ready, see IsReady
+func ForReady(clName string, clientSet kubernetes.Interface, client dynamic.Interface, mapper meta.RESTMapper, obj *unstructured.Unstructured, timeout time.Duration) error {
+	return ForObjectsReady(clName, clientSet, client, mapper, []*unstructured.Unstructured{obj}, timeout)
+}
+
+// ForDeploymentReady waits up to timeout for the deployment roll out
+func ForDeploymentReady(clName string, clientSet kubernetes.Interface, client dynamic.Interface, mapper meta.RESTMapper, namespace, name string, timeout time.Duration) error {
+	return ForReady(clName, clientSet, client, mapper, ObjectRef("apps/v1", "Deployment", namespace, name), timeout)
+}
+
+// ForDaemonSetReady waits up to timeout for the daemon set roll out
+func ForDaemonSetReady(clName string, clientSet kubernetes.Interface, client dynamic.Interface, mapper meta.RESTMapper, namespace, name string, timeout time.Duration) error {
+	return ForReady(clName, clientSet, client, mapper, ObjectRef("apps/v1", "DaemonSet", namespace, name), timeout)
+}
+
+// ForStatefulSetReady waits up to timeout for the stateful set roll out
+func ForStatefulSetReady(clName string, clientSet kubernetes.Interface, client dynamic.Interface, mapper meta.RESTMapper, namespace, name string, timeout time.Duration) error {
+	return ForReady(clName, clientSet, client, mapper, ObjectRef("apps/v1", "StatefulSet", namespace, name), timeout)
+}
+
+// untilReady watches the object until it is ready or the context is done and returns the last seen state of the object.
+// Services are ready when their endpoints are, services without a selector have no endpoints managed by the cluster and
+// are ready right away.
+func untilReady(ctx context.Context, clName string, clientSet kubernetes.Interface, client dynamic.Interface, mapper meta.RESTMapper, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	if obj.GetKind() == "Service" {
+		selector, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector")
+		if len(selector) == 0 {
+			return obj, nil
+		}
+		obj = ObjectRef("v1", "Endpoints", obj.GetNamespace(), obj.GetName())
+	}
+
+	gvk := obj.GroupVersionKind()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolving %s", gvk)
+	}
+
+	var resourceClient dynamic.ResourceInterface = client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		namespace := obj.GetNamespace()
+		if namespace == "" {
+			namespace = metav1.NamespaceDefault
+		}
+		resourceClient = client.Resource(mapping.Resource).Namespace(namespace)
+	}
+
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", obj.GetName()).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return resourceClient.List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return resourceClient.Watch(options)
+		},
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the last seen state of the object has the selector of its pods
+	var mutex sync.Mutex
+	var last *unstructured.Unstructured
+	lastSeen := func() *unstructured.Unstructured {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return last
+	}
+
+	// the watch only sees the object, its pods are checked in the background and cancel the watch if one of them fails
+	var podErr error
+	podsChecked := make(chan struct{})
+	go func() {
+		defer close(podsChecked)
+		if clientSet == nil {
+			return
+		}
+		ticker := time.NewTicker(podCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				live := lastSeen()
+				if live == nil {
+					continue
+				}
+				if podErr = checkPods(clientSet, live); podErr != nil {
+					cancel()
+					return
+				}
 			}
+		}
+	}()
+
+	log.Debugf("Waiting for %s %s to be ready in %s ...", obj.GetKind(), obj.GetName(), clName)
+	_, err = watchtools.UntilWithSync(ctx, lw, &unstructured.Unstructured{}, nil, func(event watch.Event) (bool, error) {
+		live, ok := event.Object.(*unstructured.Unstructured)
+		if !ok || live.GetName() != obj.GetName() {
+			return false, nil
+		}
+		if event.Type == watch.Deleted {
+			return false, errors.Errorf("%s %s was deleted from %s", obj.GetKind(), obj.GetName(), clName)
+		}
+		mutex.Lock()
+		last = live
+		mutex.Unlock()
+
+		ready, state, err := IsReady(live)
+		if err != nil {
+			return false, err
+		}
+		if ready {
+			log.Infof("✔ %s %s is ready in %s, %s.", obj.GetKind(), obj.GetName(), clName, state)
 		} else {
-			log.Debugf("Still waiting for %s daemon set roll out %s ...", daemonSetName, clName)
+			log.Debugf("Still waiting for %s %s in %s, %s.", obj.GetKind(), obj.GetName(), clName, state)
 		}
-	}, 5*time.Second, deploymentContext.Done())
-	err := deploymentContext.Err()
-	if err != nil && err != context.Canceled {
-		return errors.Wrapf(err, "Error waiting for %s daemon set roll out.", daemonSetName)
+		return ready, nil
+	})
+	cancel()
+	<-podsChecked
+	if podErr != nil {
+		return lastSeen(), podErr
 	}
-	return nil
+	return lastSeen(), err
+}
+
+// ObjectRef returns an object with only its type and name set
+func ObjectRef(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
 }
@@ -0,0 +1,324 @@
+package wait_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dimaunx/armada/pkg/wait"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+)
+
+// newTestMapper returns a rest mapper of the kinds used by the tests
+func newTestMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Service"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Endpoints"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	return mapper
+}
+
+// newPod returns a pod of the demo deployment with the container state
+func newPod(name string, state corev1.ContainerState, restarts int32) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "demo"}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "demo", Image: "demo:latest", State: state, RestartCount: restarts},
+			},
+		},
+	}
+}
+
+// started sets the start time of the pod
+func started(pod *corev1.Pod, ago time.Duration) *corev1.Pod {
+	startTime := metav1.NewTime(time.Now().Add(-ago))
+	pod.Status.StartTime = &startTime
+	return pod
+}
+
+// object decodes a yaml object
+func object(manifest string) *unstructured.Unstructured {
+	data, err := yaml.YAMLToJSON([]byte(manifest))
+	Ω(err).ShouldNot(HaveOccurred())
+
+	obj := &unstructured.Unstructured{}
+	err = obj.UnmarshalJSON(data)
+	Ω(err).ShouldNot(HaveOccurred())
+	return obj
+}
+
+const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: demo
+  namespace: default
+  generation: 2
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: demo
+status:
+  observedGeneration: 2
+  replicas: 2
+  updatedReplicas: 2
+  readyReplicas: 1
+`
+
+func TestWait(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Wait test suite")
+}
+
+var _ = Describe("Wait tests", func() {
+	Context("Readiness", func() {
+		It("Should wait for all deployment replicas to be ready", func() {
+			obj := object(deployment)
+			ready, state, err := wait.IsReady(obj)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(ready).Should(BeFalse())
+			Expect(state).Should(Equal("2 of 2 replicas updated, 1 ready"))
+
+			Ω(unstructured.SetNestedField(obj.Object, int64(2), "status", "readyReplicas")).ShouldNot(HaveOccurred())
+			ready, _, err = wait.IsReady(obj)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(ready).Should(BeTrue())
+		})
+		It("Should wait for the latest generation to be observed", func() {
+			obj := object(deployment)
+			Ω(unstructured.SetNestedField(obj.Object, int64(2), "status", "readyReplicas")).ShouldNot(HaveOccurred())
+			obj.SetGeneration(3)
+
+			ready, _, err := wait.IsReady(obj)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(ready).Should(BeFalse())
+		})
+		It("Should treat a daemon set without scheduled pods as ready once observed", func() {
+			obj := object(`apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: gpu-plugin
+  namespace: kube-system
+  generation: 1
+`)
+			ready, _, err := wait.IsReady(obj)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(ready).Should(BeFalse())
+
+			Ω(unstructured.SetNestedField(obj.Object, int64(1), "status", "observedGeneration")).ShouldNot(HaveOccurred())
+			ready, state, err := wait.IsReady(obj)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(ready).Should(BeTrue())
+			Expect(state).Should(Equal("0 of 0 pods updated, 0 ready"))
+
+			Ω(unstructured.SetNestedField(obj.Object, int64(2), "status", "desiredNumberScheduled")).ShouldNot(HaveOccurred())
+			Ω(unstructured.SetNestedField(obj.Object, int64(2), "status", "updatedNumberScheduled")).ShouldNot(HaveOccurred())
+			Ω(unstructured.SetNestedField(obj.Object, int64(1), "status", "numberReady")).ShouldNot(HaveOccurred())
+			ready, _, err = wait.IsReady(obj)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(ready).Should(BeFalse())
+		})
+		It("Should fail on a failed job", func() {
+			obj := object(`apiVersion: batch/v1
+kind: Job
+metadata:
+  name: demo
+status:
+  conditions:
+  - type: Failed
+    status: "True"
+    message: BackoffLimitExceeded
+`)
+			_, _, err := wait.IsReady(obj)
+			Ω(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("BackoffLimitExceeded"))
+		})
+		It("Should wait for a pod to be ready", func() {
+			obj := object(`apiVersion: v1
+kind: Pod
+metadata:
+  name: demo
+status:
+  phase: Running
+  conditions:
+  - type: Ready
+    status: "False"
+`)
+			ready, _, err := wait.IsReady(obj)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(ready).Should(BeFalse())
+		})
+		It("Should fail on a CRD with conflicting names", func() {
+			obj := object(`apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: ippools.crd.projectcalico.org
+status:
+  conditions:
+  - type: NamesAccepted
+    status: "False"
+    message: plural is already in use
+`)
+			_, _, err := wait.IsReady(obj)
+			Ω(err).Should(HaveOccurred())
+		})
+		It("Should use the status conditions of any object", func() {
+			obj := object(`apiVersion: example.com/v1
+kind: Demo
+metadata:
+  name: demo
+status:
+  conditions:
+  - type: Ready
+    status: "False"
+    message: reconciling
+`)
+			ready, state, err := wait.IsReady(obj)
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(ready).Should(BeFalse())
+			Expect(state).Should(ContainSubstring("reconciling"))
+
+			ready, _, err = wait.IsReady(object("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: demo\n"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Expect(ready).Should(BeTrue())
+		})
+	})
+	Context("Watch", func() {
+		mapper := newTestMapper()
+		deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+		It("Should return once the object becomes ready", func() {
+			obj := object(deployment)
+			client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), obj.DeepCopy())
+
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(200 * time.Millisecond)
+				Ω(unstructured.SetNestedField(obj.Object, int64(2), "status", "readyReplicas")).ShouldNot(HaveOccurred())
+				_, err := client.Resource(deployments).Namespace("default").Update(obj, metav1.UpdateOptions{})
+				Ω(err).ShouldNot(HaveOccurred())
+			}()
+
+			err := wait.ForDeploymentReady("cl1", nil, client, mapper, "default", "demo", 10*time.Second)
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+		It("Should time out if the object is not ready", func() {
+			client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), object(deployment))
+
+			err := wait.ForDeploymentReady("cl1", nil, client, mapper, "default", "demo", time.Second)
+			Ω(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("timed out"))
+		})
+		It("Should wait for the endpoints of a service", func() {
+			service := object("apiVersion: v1\nkind: Service\nmetadata:\n  name: demo\n  namespace: default\nspec:\n  selector:\n    app: demo\n")
+			endpoints := object(`apiVersion: v1
+kind: Endpoints
+metadata:
+  name: demo
+  namespace: default
+subsets:
+- addresses:
+  - ip: 10.0.0.1
+`)
+			configMap := object("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: demo\n  namespace: default\n")
+			client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), service.DeepCopy(), configMap.DeepCopy())
+
+			err := wait.ForReady("cl1", nil, client, mapper, service, time.Second)
+			Ω(err).Should(HaveOccurred())
+
+			_, err = client.Resource(schema.GroupVersionResource{Version: "v1", Resource: "endpoints"}).Namespace("default").Create(endpoints, metav1.CreateOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			err = wait.ForObjectsReady("cl1", nil, client, mapper, []*unstructured.Unstructured{service, configMap}, 10*time.Second)
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+	})
+	Context("Diagnostics", func() {
+		mapper := newTestMapper()
+		imagePullBackOff := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}}
+		crashLoopBackOff := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+
+		It("Should detect containers that are not going to recover", func() {
+			_, _, failed := wait.FailingContainer(newPod("demo", crashLoopBackOff, 1))
+			Expect(failed).Should(BeFalse())
+
+			container, reason, failed := wait.FailingContainer(newPod("demo", crashLoopBackOff, 5))
+			Expect(failed).Should(BeTrue())
+			Expect(container).Should(Equal("demo"))
+			Expect(reason).Should(Equal("CrashLoopBackOff"))
+
+			_, _, failed = wait.FailingContainer(started(newPod("demo", imagePullBackOff, 0), 10*time.Second))
+			Expect(failed).Should(BeFalse())
+
+			_, reason, failed = wait.FailingContainer(started(newPod("demo", imagePullBackOff, 0), 5*time.Minute))
+			Expect(failed).Should(BeTrue())
+			Expect(reason).Should(Equal("ImagePullBackOff"))
+
+			invalidImageName := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "InvalidImageName"}}
+			_, reason, failed = wait.FailingContainer(started(newPod("demo", invalidImageName, 0), time.Second))
+			Expect(failed).Should(BeTrue())
+			Expect(reason).Should(Equal("InvalidImageName"))
+		})
+		It("Should fail fast with diagnostics if a pod can not pull its image", func() {
+			client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), object(deployment))
+			clientSet := fakekubernetes.NewSimpleClientset(
+				newPod("demo-1", corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}, 0),
+				started(newPod("demo-2", imagePullBackOff, 0), 5*time.Minute),
+				&corev1.Event{
+					ObjectMeta:     metav1.ObjectMeta{Name: "demo-2.1", Namespace: "default"},
+					InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "demo-2", Namespace: "default"},
+					Type:           corev1.EventTypeWarning,
+					Reason:         "Failed",
+					Message:        "Failed to pull image \"demo:latest\": not found",
+					Count:          3,
+				},
+			)
+
+			start := time.Now()
+			err := wait.ForDeploymentReady("cl1", clientSet, client, mapper, "default", "demo", time.Minute)
+			Ω(err).Should(HaveOccurred())
+			Expect(time.Since(start)).Should(BeNumerically("<", 30*time.Second))
+			Expect(err.Error()).Should(ContainSubstring("container demo of pod demo-2 is in ImagePullBackOff"))
+			Expect(err.Error()).Should(ContainSubstring("waiting ImagePullBackOff Back-off pulling image"))
+			Expect(err.Error()).Should(ContainSubstring("Warning Failed (x3): Failed to pull image \"demo:latest\": not found"))
+		})
+		It("Should select the pods by the match expressions of the selector", func() {
+			selectorDeployment := object(strings.Replace(deployment, "    matchLabels:\n      app: demo\n",
+				"    matchExpressions:\n    - {key: app, operator: In, values: [demo]}\n", 1))
+			client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), selectorDeployment)
+			invalidImageName := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "InvalidImageName"}}
+			other := newPod("alpha-1", invalidImageName, 0)
+			other.Labels = map[string]string{"app": "other"}
+			clientSet := fakekubernetes.NewSimpleClientset(other, newPod("demo-1", invalidImageName, 0))
+
+			err := wait.ForDeploymentReady("cl1", clientSet, client, mapper, "default", "demo", time.Minute)
+			Ω(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("container demo of pod demo-1 is in InvalidImageName"))
+			Expect(err.Error()).ShouldNot(ContainSubstring("alpha-1"))
+		})
+		It("Should attach the not ready pods to a timeout", func() {
+			client := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), object(deployment))
+			containerCreating := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}
+			clientSet := fakekubernetes.NewSimpleClientset(newPod("demo-1", containerCreating, 0))
+
+			err := wait.ForDeploymentReady("cl1", clientSet, client, mapper, "default", "demo", time.Second)
+			Ω(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring("timed out"))
+			Expect(err.Error()).Should(ContainSubstring("pod default/demo-1"))
+			Expect(err.Error()).Should(ContainSubstring("waiting ContainerCreating"))
+		})
+	})
+})
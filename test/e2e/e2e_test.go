@@ -46,7 +46,7 @@ func CreateEnvironment(flags *createclustercmd.CreateClusterFlagpole, provider *
 	log.SetLevel(log.DebugLevel)
 	box := packr.New("configs", "../../configs")
 
-	targetClusters, err := createclustercmd.GetTargetClusters(provider, flags)
+	targetClusters, err := createclustercmd.GetTargetClusters(provider, box, flags)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -87,6 +87,11 @@ var _ = Describe("E2E Tests", func() {
 		kind.ProviderWithLogger(kindcmd.NewLogger()),
 	)
 
+	var _ = BeforeSuite(func() {
+		err := defaults.SetHome("./output")
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
 	var _ = AfterSuite(func() {
 		_ = os.RemoveAll("./output")
 	})
@@ -116,11 +121,13 @@ var _ = Describe("E2E Tests", func() {
 			Expect(clusters).Should(Equal([]*cluster.Config{
 				{
 					Cni:                 "flannel",
+					CniVersion:          "0.11.0",
 					Name:                defaults.ClusterNameBase + strconv.Itoa(1),
 					PodSubnet:           "10.0.0.0/14",
 					ServiceSubnet:       "100.0.0.0/16",
 					DNSDomain:           defaults.ClusterNameBase + strconv.Itoa(1) + ".local",
 					KubeAdminAPIVersion: defaults.KubeAdminAPIVersion,
+					KubernetesVersion:   "1.16.3",
 					NumWorkers:          defaults.NumWorkers,
 					KubeConfigFilePath:  filepath.Join(usr.HomeDir, ".kube", "kind-config-"+defaults.ClusterNameBase+strconv.Itoa(1)),
 					Retain:              false,
@@ -128,11 +135,13 @@ var _ = Describe("E2E Tests", func() {
 				},
 				{
 					Cni:                 "flannel",
+					CniVersion:          "0.11.0",
 					Name:                defaults.ClusterNameBase + strconv.Itoa(2),
 					PodSubnet:           "10.0.0.0/14",
 					ServiceSubnet:       "100.0.0.0/16",
 					DNSDomain:           defaults.ClusterNameBase + strconv.Itoa(2) + ".local",
 					KubeAdminAPIVersion: defaults.KubeAdminAPIVersion,
+					KubernetesVersion:   "1.16.3",
 					NumWorkers:          defaults.NumWorkers,
 					KubeConfigFilePath:  filepath.Join(usr.HomeDir, ".kube", "kind-config-"+defaults.ClusterNameBase+strconv.Itoa(2)),
 					Retain:              false,
@@ -174,11 +183,13 @@ var _ = Describe("E2E Tests", func() {
 			Expect(clusters).Should(Equal([]*cluster.Config{
 				{
 					Cni:                 "weave",
+					CniVersion:          "2.6.0",
 					Name:                defaults.ClusterNameBase + strconv.Itoa(3),
 					PodSubnet:           "10.12.0.0/14",
 					ServiceSubnet:       "100.3.0.0/16",
 					DNSDomain:           defaults.ClusterNameBase + strconv.Itoa(3) + ".local",
 					KubeAdminAPIVersion: "kubeadm.k8s.io/v1beta2",
+					KubernetesVersion:   "1.15.6",
 					NumWorkers:          defaults.NumWorkers,
 					KubeConfigFilePath:  filepath.Join(usr.HomeDir, ".kube", "kind-config-"+defaults.ClusterNameBase+strconv.Itoa(3)),
 					WaitForReady:        0,
@@ -226,10 +237,13 @@ var _ = Describe("E2E Tests", func() {
 					clientSet, err := cluster.GetClientSet(clName)
 					Ω(err).ShouldNot(HaveOccurred())
 
-					err = deploy.Resources(clName, clientSet, nginxDeploymentFile.String(), "Nginx")
+					dynamicClient, mapper, err := cluster.GetDynamicClient(clName)
 					Ω(err).ShouldNot(HaveOccurred())
 
-					err = wait.ForDaemonSetReady(clName, clientSet, "default", "nginx-demo")
+					err = deploy.Resources(clName, dynamicClient, mapper, nginxDeploymentFile.String(), "Nginx")
+					Ω(err).ShouldNot(HaveOccurred())
+
+					err = wait.ForDaemonSetReady(clName, clientSet, dynamicClient, mapper, "default", "nginx-demo", defaults.WaitDurationResources)
 					Ω(err).ShouldNot(HaveOccurred())
 					activeDeployments = append(activeDeployments, clName)
 					wg.Done()
@@ -246,7 +260,7 @@ var _ = Describe("E2E Tests", func() {
 			netshootDeploymentFile, err := box.Resolve("debug/netshoot-daemonset.yaml")
 			Ω(err).ShouldNot(HaveOccurred())
 
-			configFiles, err := ioutil.ReadDir(defaults.KindConfigDir)
+			configFiles, err := ioutil.ReadDir(defaults.HomePath(defaults.KindConfigDir))
 			Ω(err).ShouldNot(HaveOccurred())
 
 			var activeDeployments []string
@@ -258,10 +272,13 @@ var _ = Describe("E2E Tests", func() {
 					clientSet, err := cluster.GetClientSet(clName)
 					Ω(err).ShouldNot(HaveOccurred())
 
-					err = deploy.Resources(clName, clientSet, netshootDeploymentFile.String(), "Netshoot")
+					dynamicClient, mapper, err := cluster.GetDynamicClient(clName)
+					Ω(err).ShouldNot(HaveOccurred())
+
+					err = deploy.Resources(clName, dynamicClient, mapper, netshootDeploymentFile.String(), "Netshoot")
 					Ω(err).ShouldNot(HaveOccurred())
 
-					err = wait.ForDaemonSetReady(clName, clientSet, "default", "netshoot")
+					err = wait.ForDaemonSetReady(clName, clientSet, dynamicClient, mapper, "default", "netshoot", defaults.WaitDurationResources)
 					Ω(err).ShouldNot(HaveOccurred())
 					activeDeployments = append(activeDeployments, clName)
 					wg.Done()
@@ -281,13 +298,13 @@ var _ = Describe("E2E Tests", func() {
 			log.SetLevel(log.DebugLevel)
 
 			for _, clName := range flags.Clusters {
-				err := provider.CollectLogs(clName, filepath.Join(defaults.KindLogsDir, clName))
+				err := provider.CollectLogs(clName, defaults.HomePath(defaults.KindLogsDir, clName))
 				Ω(err).ShouldNot(HaveOccurred())
 			}
 
-			_, err := os.Stat(filepath.Join(defaults.KindLogsDir, "cluster1", "cluster1-control-plane"))
+			_, err := os.Stat(defaults.HomePath(defaults.KindLogsDir, "cluster1", "cluster1-control-plane"))
 			Ω(err).ShouldNot(HaveOccurred())
-			_, err = os.Stat(filepath.Join(defaults.KindLogsDir, "cluster2", "cluster2-control-plane"))
+			_, err = os.Stat(defaults.HomePath(defaults.KindLogsDir, "cluster2", "cluster2-control-plane"))
 			Ω(err).ShouldNot(HaveOccurred())
 
 		})
@@ -323,7 +340,7 @@ var _ = Describe("E2E Tests", func() {
 			log.SetLevel(log.DebugLevel)
 
 			var targetClusters []string
-			configFiles, err := ioutil.ReadDir(defaults.KindConfigDir)
+			configFiles, err := ioutil.ReadDir(defaults.HomePath(defaults.KindConfigDir))
 			Ω(err).ShouldNot(HaveOccurred())
 			for _, configFile := range configFiles {
 				clName := strings.FieldsFunc(configFile.Name(), func(r rune) bool { return strings.ContainsRune(" -.", r) })[2]
@@ -344,16 +361,9 @@ var _ = Describe("E2E Tests", func() {
 				defer os.RemoveAll(filepath.Dir(imageTarPath))
 
 				log.Infof("loading image: %s to nodes: %s ...", imageName, selectedNodes)
-				var wg sync.WaitGroup
-				wg.Add(len(selectedNodes))
-				for _, node := range selectedNodes {
-					go func(node nodes.Node) {
-						err = image.LoadToNode(imageTarPath, imageName, node, &wg)
-						Ω(err).ShouldNot(HaveOccurred())
-						nodesWithImage = append(nodesWithImage, node)
-					}(node)
-				}
-				wg.Wait()
+				err = image.LoadArchive(imageTarPath, imageName, selectedNodes, image.DefaultLoadConcurrency)
+				Ω(err).ShouldNot(HaveOccurred())
+				nodesWithImage = append(nodesWithImage, selectedNodes...)
 			}
 			Expect(len(nodesWithImage)).Should(Equal(9))
 		})
@@ -380,16 +390,9 @@ var _ = Describe("E2E Tests", func() {
 				defer os.RemoveAll(filepath.Dir(imageTarPath))
 
 				log.Infof("loading image: %s to nodes: %s ...", imageName, selectedNodes)
-				var wg sync.WaitGroup
-				wg.Add(len(selectedNodes))
-				for _, node := range selectedNodes {
-					go func(node nodes.Node) {
-						err = image.LoadToNode(imageTarPath, imageName, node, &wg)
-						Ω(err).ShouldNot(HaveOccurred())
-						nodesWithImages = append(nodesWithImages, node)
-					}(node)
-				}
-				wg.Wait()
+				err = image.LoadArchive(imageTarPath, imageName, selectedNodes, image.DefaultLoadConcurrency)
+				Ω(err).ShouldNot(HaveOccurred())
+				nodesWithImages = append(nodesWithImages, selectedNodes...)
 			}
 			Expect(len(nodesWithImages)).Should(Equal(12))
 		})
@@ -421,7 +424,7 @@ var _ = Describe("E2E Tests", func() {
 			Expect(cl3Status).Should(BeFalse())
 		})
 		It("Should destroy all remaining clusters", func() {
-			configFiles, err := ioutil.ReadDir(defaults.KindConfigDir)
+			configFiles, err := ioutil.ReadDir(defaults.HomePath(defaults.KindConfigDir))
 			Ω(err).ShouldNot(HaveOccurred())
 
 			for _, file := range configFiles {